@@ -12,10 +12,12 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"net/http/cookiejar"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/NearlyUnique/httptestclient/internal/self"
 )
@@ -46,6 +48,11 @@ type testingHooks interface {
 	Failed() bool
 }
 
+// logger testing clients do not need to expose this, when available the transcript is logged on failure
+type logger interface {
+	Logf(format string, args ...interface{})
+}
+
 // ErrNilBodyJSON sentinel error
 var ErrNilBodyJSON = errors.New("BodyJson requires non nil value")
 
@@ -55,6 +62,7 @@ type SimpleResponse struct {
 	Body          string
 	Status        int
 	RedirectedVia string
+	Redirects     []RedirectHop
 	Response      *http.Response
 
 	t TestingT
@@ -76,16 +84,37 @@ type Client struct {
 	// MaxRedirects, (per request) default is 10
 	MaxRedirects int
 
-	method             string
-	url                string
-	header             http.Header
-	body               io.Reader
-	form               url.Values
-	context            context.Context
-	expectedStatus     int
-	err                error
-	expectRedirectPath string
-	actualRedirect     []string
+	method              string
+	url                 string
+	urlArgs             []any
+	strictURL           bool
+	header              http.Header
+	body                []byte
+	bodyTemplate        bool
+	sentBody            []byte
+	vars                map[string]any
+	captures            map[string]Capture
+	form                url.Values
+	query               url.Values
+	querySent           bool
+	multipart           []multipartPart
+	multipartBoundary   string
+	context             context.Context
+	expectedStatus      int
+	expect2xx           bool
+	err                 error
+	expectRedirectPath  string
+	expectRedirectChain []string
+	expectChain         bool
+	noFollowRedirects   bool
+	redirects           []RedirectHop
+	session             *Session
+	transcript          []*exchange
+	sending             *exchange
+	exportHAR           bool
+	recordPath          string
+	curlOnFailure       bool
+	openAPI             *OpenAPI
 }
 
 // New for testing, finish with Client.Do or Client.DoSimple
@@ -107,14 +136,23 @@ func New(t TestingT) *Client {
 	h.Set("Accept", "application/json")
 	h.Set("User-Agent", UserAgent)
 
-	return &Client{
+	c := &Client{
 		t:            t,
 		context:      context.Background(),
 		method:       http.MethodGet,
 		url:          "/",
 		header:       h,
 		MaxRedirects: 10,
+		recordPath:   os.Getenv(RecordToEnv),
 	}
+	if h, ok := t.(testingHooks); ok {
+		h.Cleanup(func() {
+			if l, ok := t.(logger); ok && h.Failed() && len(c.transcript) > 0 {
+				l.Logf("%s", c.Transcript())
+			}
+		})
+	}
+	return c
 }
 
 // Context when sending the request, defaults to `context.Context()` if not set
@@ -124,22 +162,31 @@ func (c *Client) Context(ctx context.Context) *Client {
 }
 
 // ExpectedStatusCode for the test to pass. By default, any 2xx will pass otherwise explicitly state the success status
-// do not use this to expect redirects, see ExpectRedirectTo
+// do not use this to expect redirects, see ExpectRedirectTo, unless NoFollowRedirects is used
 func (c *Client) ExpectedStatusCode(status int) *Client {
-	if status >= 300 && status < 400 {
-		c.failNow("misuse of ExpectedStatusCode(%d), use ExpectRedirectTo instead", status)
-		return c
-	}
 	c.expectedStatus = status
 	return c
 }
 
-// ExpectRedirectTo a specific url
+// ExpectRedirectTo a specific url, only the first redirect is checked
 func (c *Client) ExpectRedirectTo(path string) *Client {
 	c.expectRedirectPath = path
 	return c
 }
 
+// ExpectRedirectChain every path redirected to, in order, with no args no redirects are expected
+func (c *Client) ExpectRedirectChain(paths ...string) *Client {
+	c.expectRedirectChain = paths
+	c.expectChain = true
+	return c
+}
+
+// NoFollowRedirects returns any 3xx as the final response, use ExpectedStatusCode to assert the redirect status
+func (c *Client) NoFollowRedirects() *Client {
+	c.noFollowRedirects = true
+	return c
+}
+
 // Method to use in request, the default is GET
 func (c *Client) Method(method string) *Client {
 	c.method = method
@@ -147,8 +194,22 @@ func (c *Client) Method(method string) *Client {
 }
 
 // URL adds a url using formatting $index or $map_key if args is a single map[string]any, default URL is '/'
+// http.ServeMux style {index}, {map_key} and {map_key...} placeholders can also be used.
+// Values are escaped for the path or query unless wrapped in Raw, {map_key...} keeps any '/' unescaped.
+// Parameters added by Query, before or after the URL, are kept until a request is sent, see Query
 func (c *Client) URL(url string, args ...any) *Client {
-	c.url = expandStr(url, args...)
+	c.url = url
+	c.urlArgs = args
+	if c.querySent {
+		c.query = nil
+		c.querySent = false
+	}
+	return c
+}
+
+// StrictURL fails the test when a URL placeholder has no value, rather than sending [no_key:$name]
+func (c *Client) StrictURL() *Client {
+	c.strictURL = true
 	return c
 }
 
@@ -196,12 +257,13 @@ func (c *Client) Header(name, value string, moreValues ...string) *Client {
 	return c
 }
 
-// FormData for posting x-www-form-urlencoded forms
+// FormData for posting x-www-form-urlencoded forms, replaces any other kind of body
 // args is expected to be pairs of key:values
 func (c *Client) FormData(args ...string) *Client {
 	if len(args)%2 != 0 {
 		c.failNow("Incorrect number of parameters %d items, missed pair", len(args))
 	}
+	c.multipart, c.multipartBoundary = nil, ""
 	if c.form == nil {
 		c.form = url.Values{}
 	}
@@ -209,19 +271,72 @@ func (c *Client) FormData(args ...string) *Client {
 		c.form.Add(args[i], args[i+1])
 	}
 	// re-encode fom as body
-	c.body = strings.NewReader(c.form.Encode())
+	c.body = []byte(c.form.Encode())
+	c.bodyTemplate = false
+	return c
+}
+
+// Var for expansion of ${name} in the URL, headers and BodyString, the store belongs to the Client and is kept
+// between requests. Values may be maps or structs, ${name.Field}. ${ENV:NAME} reads the environment and
+// ${name:-default} is used when the value is unset or empty, ${name-default} only when unset, $${name} is sent as
+// ${name}. Any other variable without a value fails the test
+func (c *Client) Var(name string, value any) *Client {
+	if c.vars == nil {
+		c.vars = map[string]any{}
+	}
+	c.vars[name] = value
 	return c
 }
 
+// Vars sets each variable, see Var
+func (c *Client) Vars(vars map[string]any) *Client {
+	for k, v := range vars {
+		c.Var(k, v)
+	}
+	return c
+}
+
+// CaptureVar sets the variable name from the json path of the next successful response, for use in later requests
+// from the same Client, e.g. CaptureVar("id", "$.id") then Get("/items/${id}"), see CaptureJSONPath
+func (c *Client) CaptureVar(name, jsonPath string) *Client {
+	if c.captures == nil {
+		c.captures = map[string]Capture{}
+	}
+	c.captures[name] = CaptureJSONPath(name, jsonPath)
+	return c
+}
+
+// captureVars false if any capture failed, captures are made in name order
+func (c *Client) captureVars(body []byte) bool {
+	if h, ok := c.t.(testingHooks); ok {
+		h.Helper()
+	}
+	if c.vars == nil {
+		c.vars = map[string]any{}
+	}
+	r := SimpleResponse{Body: string(body), t: c.t}
+	for _, name := range sortedKeys(c.captures) {
+		if err := c.captures[name](r, c.vars); err != nil {
+			c.failNow("capture of '%s' failed, %v", name, err)
+			return false
+		}
+	}
+	c.captures = nil
+	return true
+}
+
 // ClearHeaders removes default http headers, Accept, Content-Type, User-Agent. Must be called before adding other headers
 func (c *Client) ClearHeaders() *Client {
 	c.header = make(http.Header)
 	return c
 }
 
-// BodyBytes to send
+// BodyBytes to send, replaces any FormData or multipart body
 func (c *Client) BodyBytes(body []byte) *Client {
-	c.body = bytes.NewReader(body)
+	c.form = nil
+	c.multipart, c.multipartBoundary = nil, ""
+	c.body = body
+	c.bodyTemplate = false
 	return c
 }
 
@@ -242,11 +357,13 @@ func (c *Client) BodyJSON(payload any) *Client {
 	return c.BodyBytes(buf)
 }
 
-// BodyString is a literal string version of the body
+// BodyString is a literal string version of the body, variables such as ${NAME} are expanded, see Var
 // if the body has a value the content-type will be 'application/json' will be added
 // unless you set an alternative or use ClearHeaders()
 func (c *Client) BodyString(body string) *Client {
-	return c.BodyBytes([]byte(body))
+	c.BodyBytes([]byte(body))
+	c.bodyTemplate = true
+	return c
 }
 
 // BuildRequest a raw unsent request
@@ -254,86 +371,216 @@ func (c *Client) BuildRequest() *http.Request {
 	if h, ok := c.t.(testingHooks); ok {
 		h.Helper()
 	}
-	return c.buildRequest("")
+	return c.buildRequest("", c.openAPI)
 }
 
-func (c *Client) buildRequest(baseURL string) *http.Request {
+func (c *Client) buildRequest(baseURL string, doc *OpenAPI) *http.Request {
 	if h, ok := c.t.(testingHooks); ok {
 		h.Helper()
 	}
 	if c.err != nil {
 		return nil
 	}
-	urlPath := joinPath(baseURL, c.url)
-	if len(c.form) > 0 && c.method == "" {
-		c.Method(http.MethodPost)
+	req, body, err := c.newRequest(baseURL)
+	var re *requestError
+	if errors.As(err, &re) {
+		c.failNow(re.format, re.args...)
+		return nil
 	}
-	c.actualRedirect = nil
-	req, err := http.NewRequestWithContext(c.context, c.method, urlPath, c.body)
 	if c.hasError(err) {
 		return nil
 	}
-	req.Header = c.header
-	if len(c.form) > 0 {
+	c.sentBody = body
+	if !c.validateRequest(doc, baseURL, req, body) {
+		return nil
+	}
+	return req
+}
+
+// requestError a request could not be built, reported with failNow
+type requestError struct {
+	format string
+	args   []any
+}
+
+func (e *requestError) Error() string {
+	return fmt.Sprintf(e.format, e.args...)
+}
+
+// newRequest with variables and placeholders expanded, body is the expanded body that was sent, the client is not modified
+func (c *Client) newRequest(baseURL string) (*http.Request, []byte, error) {
+	path, missing := expandURL(c.url, c.vars, c.urlArgs...)
+	if len(missing) > 0 && (c.strictURL || hasVariable(missing)) {
+		return nil, nil, &requestError{"url '%s' has no value for %s", []any{c.url, strings.Join(missing, ", ")}}
+	}
+	header := c.header.Clone()
+	for name, values := range header {
+		for i, v := range values {
+			if values[i], missing = expandVars(v, c.vars); len(missing) > 0 {
+				return nil, nil, &requestError{"header '%s' has no value for %s", []any{name, strings.Join(missing, ", ")}}
+			}
+		}
+	}
+	sent := c.body
+	if c.bodyTemplate {
+		s, missing := expandVars(string(c.body), c.vars)
+		if len(missing) > 0 {
+			return nil, nil, &requestError{"body has no value for %s", []any{strings.Join(missing, ", ")}}
+		}
+		sent = []byte(s)
+	}
+	urlPath := c.withQuery(joinPath(baseURL, path))
+	method := c.method
+	if len(c.form) > 0 && method == "" {
+		method = http.MethodPost
+	}
+	var body io.Reader
+	if sent != nil {
+		body = bytes.NewReader(sent)
+	}
+	req, err := http.NewRequestWithContext(c.context, method, urlPath, body)
+	if err != nil {
+		return nil, nil, err
+	}
+	// cloned as the http.Client adds cookies to the request headers
+	req.Header = header
+	if len(c.multipart) > 0 {
+		req.Header.Set("Content-Type", "multipart/form-data; boundary="+c.multipartBoundary)
+	} else if len(c.form) > 0 {
 		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	} else if c.body != nil && req.Header.Get("Content-Type") == "" {
 		req.Header.Set("Content-Type", DefaultContentType)
 	}
-	return req
+	return req, sent, nil
 }
 
 // Do the http request, http status must either match expected or be success
 func (c *Client) Do(server *httptest.Server) *http.Response {
+	if h, ok := c.t.(testingHooks); ok {
+		h.Helper()
+	}
+	return c.DoTarget(Server(server))
+}
 
+// DoTarget performs as Do but against any Target, e.g. Handler or BaseURL.
+// The response body is only read before returning when it is needed, by CaptureVar, OpenAPI or RecordTo,
+// otherwise it is recorded for the transcript as it is read so streamed responses arrive as they are sent
+func (c *Client) DoTarget(target Target) (resp *http.Response) {
 	if h, ok := c.t.(testingHooks); ok {
 		h.Helper()
 	}
 
-	req := c.buildRequest(server.URL)
-	if req == nil {
+	if c.expectedStatus >= 300 && c.expectedStatus < 400 && !c.noFollowRedirects {
+		c.failNow("misuse of ExpectedStatusCode(%d), use ExpectRedirectTo instead", c.expectedStatus)
 		return nil
 	}
-	client := server.Client()
-
-	if client.Jar == nil {
-		// cookiejar.New() NEVER returns an error
-		jar, _ := cookiejar.New(nil)
-		client.Jar = jar
+	c.sending = nil
+	session := c.sessionFor(target)
+	doc := c.openAPIFor(session)
+	req := c.buildRequest(target.BaseURL(), doc)
+	if req == nil {
+		return nil
 	}
-
+	c.querySent = true
+	c.redirects = nil
+	// copy so the redirect policy applies to this request only, the jar and transport remain shared with the session
+	client := *session.client
+
+	_, inProcess := session.target.(*handlerTarget)
+	e := &exchange{
+		requestBody: c.sentBody,
+		requestSize: len(c.sentBody),
+		keepBodies:  c.exportHAR || session.exportingHAR(),
+		multipart:   slices.Clip(c.multipart),
+		inProcess:   inProcess,
+	}
+	req = e.trace(req)
+	e.request = req
+	c.sending = e
+	defer func() {
+		if resp == nil {
+			e.drain()
+		}
+	}()
+	expectRedirectPath := c.expectRedirectPath
 	wasRedirected := false
-	if client.CheckRedirect == nil {
-		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
-			c.actualRedirect = append(c.actualRedirect, req.URL.Path)
-			if c.expectRedirectPath != "" && req.URL.Path != c.expectRedirectPath {
-				c.failNow("expected to redirect path '%s', actual path '%s'", c.expectRedirectPath, req.URL.Path)
-				return fmt.Errorf("expected to redirect path '%s', actual path '%s'", c.expectRedirectPath, req.URL.Path)
-			}
-			if len(c.actualRedirect) > c.MaxRedirects {
-				c.failNow("exceeded Client::MaxRedirects (%d) currently to '%s'", c.MaxRedirects, req.URL.Path)
-				return fmt.Errorf("exceeded max redirects of %d currently to '%s'", c.MaxRedirects, req.URL.Path)
-			}
-			wasRedirected = true
-			// clear the re-direct
-			c.expectRedirectPath = ""
-			return nil
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if c.noFollowRedirects {
+			return http.ErrUseLastResponse
+		}
+		c.redirects = append(c.redirects, newRedirectHop(req))
+		e.hops = append(e.hops, hopResponse{response: req.Response, at: time.Now()})
+		if expectRedirectPath != "" && req.URL.Path != expectRedirectPath {
+			c.failNow("expected to redirect path '%s', actual path '%s'", expectRedirectPath, req.URL.Path)
+			return fmt.Errorf("expected to redirect path '%s', actual path '%s'", expectRedirectPath, req.URL.Path)
 		}
+		if len(c.redirects) > c.MaxRedirects {
+			c.failNow("exceeded Client::MaxRedirects (%d) currently to '%s'", c.MaxRedirects, req.URL.Path)
+			return fmt.Errorf("exceeded max redirects of %d currently to '%s'", c.MaxRedirects, req.URL.Path)
+		}
+		wasRedirected = true
+		// only the first hop is checked
+		expectRedirectPath = ""
+		if session.client.CheckRedirect != nil {
+			return session.client.CheckRedirect(req, via)
+		}
+		return nil
 	}
+	e.started = time.Now()
+	if n := len(c.transcript); n > 0 {
+		c.transcript[n-1].trim()
+	}
+	c.transcript = append(c.transcript, e)
+	session.add(e)
 	resp, err := client.Do(req)
+	e.redirects = c.redirects
+	if err == nil {
+		e.response = resp
+		if len(c.captures) > 0 || doc != nil || c.recordPath != "" {
+			err = e.bufferBody(resp)
+		} else {
+			e.teeBody(resp)
+		}
+	}
+	e.duration = time.Since(e.started)
 	if c.hasError(err) {
+		e.err = err
+		return nil
+	}
+	cover(doc, target.BaseURL(), e)
+	if c.hasError(c.record(e)) {
+		return nil
+	}
+	if c.noFollowRedirects && expectRedirectPath != "" {
+		wasRedirected = c.checkRedirectLocation(resp, expectRedirectPath)
+		if c.err != nil {
+			return nil
+		}
+	}
+	if expectRedirectPath != "" && !wasRedirected {
+		c.failNow("expected to redirect path '%s' but no redirection happened", expectRedirectPath)
 		return nil
 	}
-	if c.expectRedirectPath != "" && !wasRedirected {
-		c.failNow("expected to redirect path '%s' but no redirection happened", c.expectRedirectPath)
+	if c.expectChain && !slices.Equal(c.expectRedirectChain, redirectPaths(c.redirects)) {
+		c.failNow("expected redirect chain %v, actual %v", c.expectRedirectChain, redirectPaths(c.redirects))
 		return nil
 	}
 	if c.expectedStatus == 0 && resp.StatusCode >= 400 {
 		c.failNow("expected success, got %d", resp.StatusCode)
 		return nil
+	} else if c.expectedStatus == 0 && c.expect2xx && (resp.StatusCode < 200 || resp.StatusCode > 299) {
+		c.failNow("expected 2xx, got %d", resp.StatusCode)
+		return nil
 	} else if c.expectedStatus > 0 && c.expectedStatus != resp.StatusCode {
 		c.failNow("expected %d, got %d", c.expectedStatus, resp.StatusCode)
 		return nil
 	}
+	if !c.validateResponse(doc, target.BaseURL(), resp, e.body(), len(c.redirects) > 0) {
+		return nil
+	}
+	if !c.captureVars(e.body()) {
+		return nil
+	}
 
 	if _, ok := c.t.(*self.FakeTester); ok {
 		// if you get here, and you are self testing then your test has failed to fail
@@ -348,7 +595,15 @@ func (c *Client) DoSimple(server *httptest.Server) SimpleResponse {
 	if h, ok := c.t.(testingHooks); ok {
 		h.Helper()
 	}
-	resp := c.Do(server)
+	return c.DoSimpleTarget(Server(server))
+}
+
+// DoSimpleTarget performs as DoSimple but against any Target, e.g. Handler or BaseURL
+func (c *Client) DoSimpleTarget(target Target) SimpleResponse {
+	if h, ok := c.t.(testingHooks); ok {
+		h.Helper()
+	}
+	resp := c.DoTarget(target)
 	if resp == nil {
 		// test will have already failed for normal use, for self test the FakeTest will have detected the
 		return SimpleResponse{}
@@ -363,7 +618,8 @@ func (c *Client) DoSimple(server *httptest.Server) SimpleResponse {
 		Header:        resp.Header,
 		Status:        resp.StatusCode,
 		Body:          string(buf),
-		RedirectedVia: strings.Join(c.actualRedirect, ","),
+		RedirectedVia: strings.Join(redirectPaths(c.redirects), ","),
+		Redirects:     c.redirects,
 		Response:      resp,
 		t:             c.t,
 	}
@@ -399,6 +655,10 @@ func (c *Client) failNow(format string, args ...interface{}) {
 	if h, ok := c.t.(testingHooks); ok {
 		h.Helper()
 	}
+	if c.curlOnFailure && c.sending != nil {
+		format += "\nreproduce with: %s"
+		args = append(args, c.sending.curl())
+	}
 	c.err = fmt.Errorf(format, args...)
 	c.t.Errorf(format, args...)
 	c.t.FailNow()
@@ -521,3 +521,126 @@ func formHandler(t *testing.T, actual *ActualFormRequest) http.Handler {
 		defer func() { _ = r.Body.Close() }()
 	})
 }
+
+func Test_url_placeholders(t *testing.T) {
+	var actual string
+	target := httptestclient.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actual = r.URL.EscapedPath()
+	}))
+
+	t.Run("values are path escaped", func(t *testing.T) {
+		_ = httptestclient.New(t).
+			Get("/items/$0/{1}", "a/b c", "ü").
+			StrictURL().
+			DoSimpleTarget(target)
+
+		assert.Equal(t, "/items/a%2Fb%20c/%C3%BC", actual)
+	})
+	t.Run("a missing value fails in strict mode", func(t *testing.T) {
+		_ = httptestclient.New(self.NewFakeTester(func(format string, args ...interface{}) {
+			assert.Equal(t, "url '%s' has no value for %s", format)
+			require.Equal(t, 2, len(args))
+			assert.Equal(t, "/items/{id}/$1", args[0])
+			assert.Equal(t, "{id}, $1", args[1])
+		})).
+			Get("/items/{id}/$1", "any").
+			StrictURL().
+			DoSimpleTarget(target)
+	})
+}
+
+func Test_variables(t *testing.T) {
+	var actual struct {
+		path   string
+		header string
+		body   string
+	}
+	target := httptestclient.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actual.path = r.URL.EscapedPath()
+		actual.header = r.Header.Get("Authorization")
+		buf, _ := io.ReadAll(r.Body)
+		actual.body = string(buf)
+		if r.Method == http.MethodPost {
+			_, _ = w.Write([]byte(`{"id":9007199254740993,"name":"new item"}`))
+		}
+	}))
+
+	t.Run("expanded in url, headers and string bodies", func(t *testing.T) {
+		t.Setenv("HTC_TEST_TOKEN", "secret")
+
+		_ = httptestclient.New(t).
+			Post("/items/${name}").
+			Var("name", "a b").
+			Vars(map[string]any{"qty": 3}).
+			Header("Authorization", "Bearer ${ENV:HTC_TEST_TOKEN}").
+			BodyString(`{"name":"${name}","qty":${qty},"colour":"${colour:-red}"}`).
+			DoSimpleTarget(target)
+
+		assert.Equal(t, "/items/a%20b", actual.path)
+		assert.Equal(t, "Bearer secret", actual.header)
+		assert.Equal(t, `{"name":"a b","qty":3,"colour":"red"}`, actual.body)
+	})
+	t.Run("byte bodies are sent as is", func(t *testing.T) {
+		_ = httptestclient.New(t).
+			Post("/items").
+			BodyBytes([]byte(`${name}`)).
+			DoSimpleTarget(target)
+
+		assert.Equal(t, `${name}`, actual.body)
+	})
+	t.Run("captured from earlier responses", func(t *testing.T) {
+		client := httptestclient.New(t).
+			Post("/items").
+			BodyString(`{}`).
+			CaptureVar("id", "$.id")
+		_ = client.DoSimpleTarget(target)
+
+		_ = client.Get("/items/${id}").DoSimpleTarget(target)
+
+		assert.Equal(t, "/items/9007199254740993", actual.path)
+	})
+	t.Run("a capture that selects nothing fails the test", func(t *testing.T) {
+		var messages []string
+		_ = httptestclient.New(self.NewFakeTester(func(format string, args ...interface{}) {
+			messages = append(messages, fmt.Sprintf(format, args...))
+		})).
+			Post("/items").
+			BodyString(`{}`).
+			CaptureVar("ids", "$.items[*].id").
+			DoSimpleTarget(target)
+
+		require.Len(t, messages, 1)
+		assert.True(t, strings.HasPrefix(messages[0], "capture of 'ids' failed, json path '$.items[*].id' not found in "), messages[0])
+	})
+	t.Run("a capture that selects many values fails the test", func(t *testing.T) {
+		var messages []string
+		_ = httptestclient.New(self.NewFakeTester(func(format string, args ...interface{}) {
+			messages = append(messages, fmt.Sprintf(format, args...))
+		})).
+			Post("/items").
+			BodyString(`{}`).
+			CaptureVar("id", "$.*").
+			DoSimpleTarget(target)
+
+		assert.Equal(t, []string{"capture of 'id' failed, json path '$.*' selected 2 values, a capture needs exactly one"}, messages)
+	})
+	t.Run("a missing variable fails the test", func(t *testing.T) {
+		_ = httptestclient.New(self.NewFakeTester(func(format string, args ...interface{}) {
+			assert.Equal(t, "header '%s' has no value for %s", format)
+			require.Equal(t, 2, len(args))
+			assert.Equal(t, "X-Id", args[0])
+			assert.Equal(t, "${id}", args[1])
+		})).
+			Header("X-Id", "${id}").
+			DoSimpleTarget(target)
+	})
+	t.Run("a missing variable in the url fails without StrictURL", func(t *testing.T) {
+		_ = httptestclient.New(self.NewFakeTester(func(format string, args ...interface{}) {
+			assert.Equal(t, "url '%s' has no value for %s", format)
+			require.Equal(t, 2, len(args))
+			assert.Equal(t, "${id}", args[1])
+		})).
+			Get("/items/${id}").
+			DoSimpleTarget(target)
+	})
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// options for the generated test
+type options struct {
+	pkg       string
+	testName  string
+	target    string
+	source    string
+	include   *regexp.Regexp
+	bodyLimit int
+}
+
+var (
+	// rxPathParam segments that are ids rather than part of the route
+	rxPathParam = regexp.MustCompile(`^(\d+|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}|[0-9a-fA-F]{16,})$`)
+	// rxStatic responses that are page assets rather than api calls
+	rxStatic = regexp.MustCompile(`^(image/|font/|text/css|text/javascript|application/javascript)`)
+)
+
+// skippedHeaders are set by the client, the session or the browser
+var skippedHeaders = map[string]bool{
+	"Host": true, "Content-Length": true, "Connection": true, "Accept-Encoding": true, "Cookie": true,
+	"User-Agent": true, "Accept-Language": true, "Cache-Control": true, "Pragma": true, "Origin": true,
+	"Referer": true, "Upgrade-Insecure-Requests": true, "Priority": true, "Dnt": true, "Te": true,
+}
+
+// secretHeaders are read from the environment rather than written to the test
+var secretHeaders = map[string]bool{
+	"Authorization": true, "Proxy-Authorization": true, "X-Api-Key": true, "X-Auth-Token": true,
+}
+
+var builderMethods = map[string]string{
+	http.MethodGet: "Get", http.MethodPost: "Post", http.MethodPut: "Put", http.MethodPatch: "Patch", http.MethodDelete: "Delete",
+}
+
+// generate a gofmt'ed test file
+func generate(entries []entry, opts options) ([]byte, error) {
+	var body bytes.Buffer
+	_, _ = fmt.Fprintf(&body, "func %s(t *testing.T) {\n", opts.testName)
+	_, _ = fmt.Fprintf(&body, "session := httptestclient.NewSession(%s)\n", opts.target)
+	// standard library imports used by the requests
+	imports := map[string]bool{"testing": true}
+	n := 0
+	for _, e := range entries {
+		if skip(e, opts) {
+			continue
+		}
+		n++
+		body.WriteString("\n")
+		writeRequest(&body, n, e, opts, imports)
+	}
+	body.WriteString("}\n")
+	if n == 0 {
+		return nil, fmt.Errorf("no requests to generate")
+	}
+
+	var buf bytes.Buffer
+	_, _ = fmt.Fprintf(&buf, "// Generated by httptestclient-gen from %s, edit as required.\n\n", opts.source)
+	_, _ = fmt.Fprintf(&buf, "package %s\n\n", opts.pkg)
+	buf.WriteString("import (\n")
+	for _, pkg := range sortedKeys(imports) {
+		_, _ = fmt.Fprintf(&buf, "%q\n", pkg)
+	}
+	buf.WriteString("\n\"github.com/NearlyUnique/httptestclient\"\n)\n\n")
+	buf.Write(body.Bytes())
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated code is invalid: %w\n%s", err, buf.Bytes())
+	}
+	return src, nil
+}
+
+// skip preflight requests, page assets and anything not included
+func skip(e entry, opts options) bool {
+	if e.method == http.MethodOptions {
+		return true
+	}
+	if opts.include != nil && !opts.include.MatchString(e.url.Path) {
+		return true
+	}
+	return rxStatic.MatchString(e.responseHeader.Get("Content-Type"))
+}
+
+// writeRequest as a chain of builder calls, any standard library packages used are added to imports
+func writeRequest(buf *bytes.Buffer, n int, e entry, opts options, imports map[string]bool) {
+	_, _ = fmt.Fprintf(buf, "// %d %s %s\n", n, e.method, e.url.Path)
+	buf.WriteString("_ = httptestclient.New(t).\n")
+
+	pattern, args := pathPattern(e.url)
+	call := append([]string{strconv.Quote(pattern)}, args...)
+	if m, ok := builderMethods[e.method]; ok {
+		_, _ = fmt.Fprintf(buf, "%s(%s).\n", m, strings.Join(call, ", "))
+	} else {
+		_, _ = fmt.Fprintf(buf, "Method(%q).URL(%s).\n", e.method, strings.Join(call, ", "))
+	}
+	for _, q := range orderedQuery(e.url.RawQuery) {
+		_, _ = fmt.Fprintf(buf, "Query(%s).\n", quoteAll(q))
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(e.header.Get("Content-Type"))
+	isJSON := len(e.body) > 0 && json.Valid(e.body) && (mediaType == "" || strings.Contains(mediaType, "json"))
+	isForm := len(e.body) > 0 && mediaType == "application/x-www-form-urlencoded"
+	for _, k := range sortedKeys(e.header) {
+		name := http.CanonicalHeaderKey(k)
+		if skippedHeaders[name] || strings.HasPrefix(name, "Sec-") ||
+			(name == "Accept" && e.header.Get(k) == "application/json") ||
+			(name == "Content-Type" && (isJSON && strings.HasPrefix(mediaType, "application/json") || isForm)) {
+			continue
+		}
+		var values []string
+		if secretHeaders[name] {
+			values = []string{"${ENV:" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "}"}
+		} else {
+			// recorded values are literal, the Client would expand ${
+			for _, v := range e.header[k] {
+				values = append(values, strings.ReplaceAll(v, "${", "$${"))
+			}
+		}
+		_, _ = fmt.Fprintf(buf, "Header(%q, %s).\n", name, quoteAll(values))
+	}
+
+	switch {
+	case isJSON:
+		var v any
+		dec := json.NewDecoder(bytes.NewReader(e.body))
+		dec.UseNumber()
+		_ = dec.Decode(&v)
+		_, _ = fmt.Fprintf(buf, "BodyJSON(%s).\n", goLiteral(v, imports))
+	case isForm:
+		var pairs []string
+		for _, q := range orderedQuery(string(e.body)) {
+			for _, v := range q[1:] {
+				pairs = append(pairs, q[0], v)
+			}
+		}
+		_, _ = fmt.Fprintf(buf, "FormData(%s).\n", quoteAll(pairs))
+	case len(e.body) > 0 && strings.Contains(string(e.body), "${"):
+		// BodyString would expand the variables
+		_, _ = fmt.Fprintf(buf, "BodyBytes([]byte(%s)).\n", quoteRaw(string(e.body)))
+	case len(e.body) > 0:
+		_, _ = fmt.Fprintf(buf, "BodyString(%s).\n", quoteRaw(string(e.body)))
+	}
+
+	if e.status >= 300 && e.status < 400 {
+		buf.WriteString("NoFollowRedirects().\n")
+	}
+	status := statusName(e.status)
+	if strings.HasPrefix(status, "http.") {
+		imports["net/http"] = true
+	}
+	_, _ = fmt.Fprintf(buf, "ExpectedStatusCode(%s).\n", status)
+	buf.WriteString("DoSimpleTarget(session)")
+
+	responseType, _, _ := mime.ParseMediaType(e.responseHeader.Get("Content-Type"))
+	if strings.Contains(responseType, "json") && len(e.responseBody) <= opts.bodyLimit {
+		var compact bytes.Buffer
+		if json.Compact(&compact, e.responseBody) == nil && compact.Len() > 0 {
+			_, _ = fmt.Fprintf(buf, ".\nExpectJSONMatches(%s)", quoteRaw(compact.String()))
+		}
+	}
+	buf.WriteString("\n")
+}
+
+// pathPattern replaces id segments with $0, $1..., the args are Go expressions. Segments that the pattern would
+// read as a placeholder, any with $, { or }, are passed as already escaped httptestclient.Raw args
+func pathPattern(u *url.URL) (string, []string) {
+	segments := strings.Split(u.EscapedPath(), "/")
+	var args []string
+	for i, s := range segments {
+		switch {
+		case rxPathParam.MatchString(s):
+			args = append(args, strconv.Quote(s))
+		case strings.ContainsAny(s, "${}"):
+			args = append(args, "httptestclient.Raw("+strconv.Quote(s)+")")
+		default:
+			continue
+		}
+		segments[i] = "$" + strconv.Itoa(len(args)-1)
+	}
+	pattern := strings.Join(segments, "/")
+	if pattern == "" {
+		pattern = "/"
+	}
+	return pattern, args
+}
+
+// orderedQuery keys in the order they first appear, each followed by its values
+func orderedQuery(raw string) [][]string {
+	var result [][]string
+	index := map[string]int{}
+	for _, pair := range strings.Split(raw, "&") {
+		if pair == "" {
+			continue
+		}
+		k, v, _ := strings.Cut(pair, "=")
+		k, _ = url.QueryUnescape(k)
+		v, _ = url.QueryUnescape(v)
+		i, ok := index[k]
+		if !ok {
+			i = len(result)
+			index[k] = i
+			result = append(result, []string{k})
+		}
+		result[i] = append(result[i], v)
+	}
+	return result
+}
+
+// goLiteral of a json value decoded with UseNumber. Numbers that would overflow an untyped constant in an any,
+// int for integers and float64 otherwise, are written as a json.Number and encoding/json is added to imports
+func goLiteral(v any, imports map[string]bool) string {
+	switch x := v.(type) {
+	case nil:
+		return "nil"
+	case bool:
+		return strconv.FormatBool(x)
+	case json.Number:
+		if _, err := strconv.ParseInt(x.String(), 10, 0); err == nil {
+			return x.String()
+		}
+		if _, err := strconv.ParseFloat(x.String(), 64); err == nil && strings.ContainsAny(x.String(), ".eE") {
+			return x.String()
+		}
+		imports["encoding/json"] = true
+		return "json.Number(" + strconv.Quote(x.String()) + ")"
+	case string:
+		return strconv.Quote(x)
+	case []any:
+		items := make([]string, len(x))
+		nested := false
+		for i, item := range x {
+			items[i] = goLiteral(item, imports)
+			_, isMap := item.(map[string]any)
+			_, isSlice := item.([]any)
+			nested = nested || isMap || isSlice
+		}
+		if !nested {
+			return "[]any{" + strings.Join(items, ", ") + "}"
+		}
+		var sb strings.Builder
+		sb.WriteString("[]any{\n")
+		for _, item := range items {
+			sb.WriteString(item + ",\n")
+		}
+		sb.WriteString("}")
+		return sb.String()
+	case map[string]any:
+		if len(x) == 0 {
+			return "map[string]any{}"
+		}
+		var sb strings.Builder
+		sb.WriteString("map[string]any{\n")
+		for _, k := range sortedKeys(x) {
+			sb.WriteString(strconv.Quote(k) + ": " + goLiteral(x[k], imports) + ",\n")
+		}
+		sb.WriteString("}")
+		return sb.String()
+	}
+	return fmt.Sprintf("%#v", v)
+}
+
+// quoteRaw as a raw string literal when possible, it is easier to read json that way
+func quoteRaw(s string) string {
+	if strings.Contains(s, "`") || strings.Contains(s, "\r") || !strconv.CanBackquote(strings.ReplaceAll(s, "\n", "")) {
+		return strconv.Quote(s)
+	}
+	return "`" + s + "`"
+}
+
+func quoteAll(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = strconv.Quote(v)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var statusNames = map[int]string{
+	http.StatusOK: "StatusOK", http.StatusCreated: "StatusCreated", http.StatusAccepted: "StatusAccepted",
+	http.StatusNoContent: "StatusNoContent", http.StatusMovedPermanently: "StatusMovedPermanently",
+	http.StatusFound: "StatusFound", http.StatusSeeOther: "StatusSeeOther", http.StatusNotModified: "StatusNotModified",
+	http.StatusTemporaryRedirect: "StatusTemporaryRedirect", http.StatusPermanentRedirect: "StatusPermanentRedirect",
+	http.StatusBadRequest: "StatusBadRequest", http.StatusUnauthorized: "StatusUnauthorized",
+	http.StatusForbidden: "StatusForbidden", http.StatusNotFound: "StatusNotFound",
+	http.StatusMethodNotAllowed: "StatusMethodNotAllowed", http.StatusConflict: "StatusConflict",
+	http.StatusGone: "StatusGone", http.StatusPreconditionFailed: "StatusPreconditionFailed",
+	http.StatusUnprocessableEntity: "StatusUnprocessableEntity", http.StatusTooManyRequests: "StatusTooManyRequests",
+	http.StatusInternalServerError: "StatusInternalServerError", http.StatusServiceUnavailable: "StatusServiceUnavailable",
+}
+
+func statusName(status int) string {
+	if name, ok := statusNames[status]; ok {
+		return "http." + name
+	}
+	return strconv.Itoa(status)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/NearlyUnique/httptestclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_generated_tests_match_golden_files(t *testing.T) {
+	testData := []struct {
+		golden string
+		args   []string
+	}{
+		{"traffic.golden", []string{"-package", "api_test", "testdata/traffic.har"}},
+		{"orders.golden", []string{"-include", "^/orders", "-target", "httptestclient.Server(server)", "testdata/orders.jsonl"}},
+		{"edge.golden", []string{"-package", "api_test", "testdata/edge.jsonl"}},
+	}
+	for _, td := range testData {
+		t.Run(td.golden, func(t *testing.T) {
+			out := filepath.Join(t.TempDir(), "out_test.go")
+
+			require.NoError(t, run(append([]string{"-out", out}, td.args...)))
+
+			expected, err := os.ReadFile(filepath.Join("testdata", td.golden))
+			require.NoError(t, err)
+			actual, err := os.ReadFile(out)
+			require.NoError(t, err)
+			assert.Equal(t, string(expected), string(actual))
+		})
+	}
+}
+
+func Test_generated_tests_compile(t *testing.T) {
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go is not on the path")
+	}
+	root, err := filepath.Abs("../..")
+	require.NoError(t, err)
+	goSum, err := os.ReadFile(filepath.Join(root, "go.sum"))
+	require.NoError(t, err)
+	// a module that uses this one, the generated tests are compiled by vet
+	dir := t.TempDir()
+	goMod := "module generated\n\ngo 1.25\n\nrequire github.com/NearlyUnique/httptestclient v0.0.0\n\n" +
+		"replace github.com/NearlyUnique/httptestclient => " + root + "\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte(goMod), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.sum"), goSum, 0o644))
+	for _, in := range []string{"testdata/traffic.har", "testdata/edge.jsonl"} {
+		out := filepath.Join(dir, filepath.Base(in)+"_test.go")
+		require.NoError(t, run([]string{"-package", "generated_test", "-out", out, in}))
+	}
+
+	cmd := exec.Command(goTool, "vet", ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off")
+	output, err := cmd.CombinedOutput()
+
+	assert.NoError(t, err, string(output))
+}
+
+func Test_binary_bodies_from_an_exported_har_are_generated_as_sent(t *testing.T) {
+	dir := t.TempDir()
+	har := filepath.Join(dir, "binary.har")
+	sent := []byte{0x89, 'P', 'N', 'G', 0x00, 0xff, '$', '{', 'x', '}', '`'}
+	target := httptestclient.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Run("export", func(t *testing.T) {
+		_ = httptestclient.New(t).ExportHAR(har).
+			Post("/images").Header("Content-Type", "image/png").BodyBytes(sent).
+			DoSimpleTarget(target)
+	})
+	out := filepath.Join(dir, "binary_test.go")
+
+	require.NoError(t, run([]string{"-out", out, har}))
+
+	file, err := parser.ParseFile(token.NewFileSet(), out, nil, 0)
+	require.NoError(t, err)
+	var body []byte
+	ast.Inspect(file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		if sel, ok := call.Fun.(*ast.SelectorExpr); ok && (sel.Sel.Name == "BodyString" || sel.Sel.Name == "BodyBytes") {
+			arg := call.Args[0]
+			if conv, ok := arg.(*ast.CallExpr); ok {
+				// []byte("...")
+				arg = conv.Args[0]
+			}
+			s, err := strconv.Unquote(arg.(*ast.BasicLit).Value)
+			require.NoError(t, err)
+			body = []byte(s)
+		}
+		return true
+	})
+	assert.Equal(t, sent, body)
+}
+
+func Test_generator_errors(t *testing.T) {
+	testData := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"no input", nil, "one input file is required"},
+		{"bad include", []string{"-include", "(", "testdata/orders.jsonl"}, "invalid -include: error parsing regexp: missing closing ): `(`"},
+		{"nothing included", []string{"-include", "^/none", "testdata/orders.jsonl"}, "no requests to generate"},
+		{"missing file", []string{"testdata/missing.har"}, "open testdata/missing.har: no such file or directory"},
+	}
+	for _, td := range testData {
+		t.Run(td.name, func(t *testing.T) {
+			err := run(append([]string{"-out", filepath.Join(t.TempDir(), "out_test.go")}, td.args...))
+
+			assert.EqualError(t, err, td.expected)
+		})
+	}
+}
+
+func Test_test_name_from_file(t *testing.T) {
+	assert.Equal(t, "Test_traffic_2024_01", testName("dir/traffic-2024.01.har"))
+}
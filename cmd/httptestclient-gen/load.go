@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/NearlyUnique/httptestclient"
+)
+
+// entry a request and its response from either input format
+type entry struct {
+	method         string
+	url            *url.URL
+	header         http.Header
+	body           []byte
+	status         int
+	responseHeader http.Header
+	responseBody   []byte
+}
+
+// harFile the parts of HAR 1.2 that are used
+type harFile struct {
+	Log struct {
+		Entries []struct {
+			Request struct {
+				Method   string
+				URL      string
+				Headers  []harNameValue
+				PostData *struct {
+					MimeType string
+					Text     string
+					Encoding string
+				}
+			}
+			Response struct {
+				Status  int
+				Headers []harNameValue
+				Content struct {
+					MimeType string
+					Text     string
+					Encoding string
+				}
+			}
+		}
+	}
+}
+
+type harNameValue struct {
+	Name  string
+	Value string
+}
+
+// load a .har file or a JSON lines recording, any other extension is treated as JSON lines
+func load(path string) ([]entry, error) {
+	if strings.EqualFold(filepath.Ext(path), ".har") {
+		return loadHAR(path)
+	}
+	recordings, err := httptestclient.LoadRecordings(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	var entries []entry
+	for i, rec := range recordings {
+		u, err := url.Parse(rec.URL)
+		if err != nil {
+			return nil, fmt.Errorf("%s: recording %d: %w", path, i+1, err)
+		}
+		entries = append(entries, entry{
+			method:         rec.Method,
+			url:            u,
+			header:         rec.Header,
+			body:           rec.Body,
+			status:         rec.Status,
+			responseHeader: rec.ResponseHeader,
+			responseBody:   rec.ResponseBody,
+		})
+	}
+	return entries, nil
+}
+
+func loadHAR(path string) ([]entry, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var h harFile
+	if err = json.Unmarshal(buf, &h); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	var entries []entry
+	for i, e := range h.Log.Entries {
+		u, err := url.Parse(e.Request.URL)
+		if err != nil {
+			return nil, fmt.Errorf("%s: entry %d: %w", path, i+1, err)
+		}
+		en := entry{
+			method:         e.Request.Method,
+			url:            u,
+			header:         harHeader(e.Request.Headers),
+			status:         e.Response.Status,
+			responseHeader: harHeader(e.Response.Headers),
+			responseBody:   []byte(e.Response.Content.Text),
+		}
+		if e.Request.PostData != nil {
+			en.body = []byte(e.Request.PostData.Text)
+			// not part of HAR 1.2, httptestclient writes bodies that are not UTF-8 as base64
+			if e.Request.PostData.Encoding == "base64" {
+				if en.body, err = base64.StdEncoding.DecodeString(e.Request.PostData.Text); err != nil {
+					return nil, fmt.Errorf("%s: entry %d: %w", path, i+1, err)
+				}
+			}
+			if en.header.Get("Content-Type") == "" && e.Request.PostData.MimeType != "" {
+				en.header.Set("Content-Type", e.Request.PostData.MimeType)
+			}
+		}
+		if e.Response.Content.Encoding == "base64" {
+			if en.responseBody, err = base64.StdEncoding.DecodeString(e.Response.Content.Text); err != nil {
+				return nil, fmt.Errorf("%s: entry %d: %w", path, i+1, err)
+			}
+		}
+		if en.responseHeader.Get("Content-Type") == "" && e.Response.Content.MimeType != "" {
+			en.responseHeader.Set("Content-Type", e.Response.Content.MimeType)
+		}
+		entries = append(entries, en)
+	}
+	return entries, nil
+}
+
+func harHeader(values []harNameValue) http.Header {
+	h := http.Header{}
+	for _, nv := range values {
+		// http/2 pseudo headers, e.g. :authority
+		if strings.HasPrefix(nv.Name, ":") {
+			continue
+		}
+		h.Add(nv.Name, nv.Value)
+	}
+	return h
+}
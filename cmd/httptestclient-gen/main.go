@@ -0,0 +1,77 @@
+// Command httptestclient-gen converts recorded traffic, a HAR file or a JSON lines recording from
+// httptestclient.RecordTo, into a Go test using httptestclient
+//
+//	httptestclient-gen -package api_test -out traffic_test.go traffic.har
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "httptestclient-gen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	fs := flag.NewFlagSet("httptestclient-gen", flag.ContinueOnError)
+	var opts options
+	out := fs.String("out", "", "output file, default stdout")
+	fs.StringVar(&opts.pkg, "package", "main_test", "package of the generated test")
+	fs.StringVar(&opts.testName, "name", "", "name of the test function, default from the input file name")
+	fs.StringVar(&opts.target, "target", `httptestclient.BaseURL("http://localhost:8080", nil)`, "Go expression for the httptestclient.Target")
+	include := fs.String("include", "", "only requests with a path matching this regular expression")
+	fs.IntVar(&opts.bodyLimit, "body-limit", 4096, "json responses larger than this are not asserted")
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "usage: httptestclient-gen [flags] traffic.har|traffic.jsonl\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("one input file is required")
+	}
+	in := fs.Arg(0)
+	if *include != "" {
+		rx, err := regexp.Compile(*include)
+		if err != nil {
+			return fmt.Errorf("invalid -include: %w", err)
+		}
+		opts.include = rx
+	}
+	if opts.testName == "" {
+		opts.testName = testName(in)
+	}
+	opts.source = filepath.Base(in)
+
+	entries, err := load(in)
+	if err != nil {
+		return err
+	}
+	src, err := generate(entries, opts)
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return os.WriteFile(*out, src, 0o644)
+}
+
+var rxNotIdent = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// testName from the file name, traffic-2024.har becomes Test_traffic_2024
+func testName(path string) string {
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return "Test_" + strings.Trim(rxNotIdent.ReplaceAllString(base, "_"), "_")
+}
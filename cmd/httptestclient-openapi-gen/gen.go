@@ -0,0 +1,561 @@
+package main
+
+import (
+	"fmt"
+	"go/format"
+	"go/token"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/NearlyUnique/httptestclient/internal/openapi"
+)
+
+// initialisms written in upper case in Go names
+var initialisms = map[string]bool{
+	"API": true, "HTTP": true, "ID": true, "IP": true, "JSON": true, "URI": true, "URL": true, "UUID": true,
+}
+
+// reserved names of the generated constructor arguments
+var reserved = map[string]bool{"t": true, "target": true, "body": true, "params": true, "c": true}
+
+// generator of the types and operations of a document
+type generator struct {
+	doc        *openapi.Document
+	decls      []string
+	named      map[string]bool
+	types      map[string]string
+	operations map[string]bool
+	usesFmt    bool
+}
+
+// generate a gofmt'ed file with a type per component schema and a constructor, call type and Expect methods per operation
+func generate(doc *openapi.Document, pkg, source string) ([]byte, error) {
+	ops := doc.Operations()
+	if len(ops) == 0 {
+		return nil, fmt.Errorf("no operations to generate")
+	}
+	g := &generator{doc: doc, named: map[string]bool{}, types: map[string]string{}, operations: map[string]bool{}}
+	components, _ := doc.Root()["components"].(map[string]any)
+	schemas, _ := components["schemas"].(map[string]any)
+	// named first as a $ref may be to a later component
+	used := map[string]bool{}
+	for _, name := range sortedKeys(schemas) {
+		g.types[name] = unique(used, goName(name))
+	}
+	for _, name := range sortedKeys(schemas) {
+		g.component(name, schemas[name])
+	}
+	var calls []string
+	for _, op := range ops {
+		calls = append(calls, g.operation(op))
+	}
+
+	var buf strings.Builder
+	_, _ = fmt.Fprintf(&buf, "// Code generated by httptestclient-openapi-gen from %s. DO NOT EDIT.\n\n", source)
+	_, _ = fmt.Fprintf(&buf, "package %s\n\nimport (\n", pkg)
+	if g.usesFmt {
+		buf.WriteString("\t\"fmt\"\n")
+	}
+	buf.WriteString("\t\"net/http\"\n\n\t\"github.com/NearlyUnique/httptestclient\"\n)\n\n")
+	buf.WriteString(strings.Join(append(g.decls, calls...), "\n"))
+	src, err := format.Source([]byte(buf.String()))
+	if err != nil {
+		return nil, fmt.Errorf("generated code is invalid: %w\n%s", err, buf.String())
+	}
+	return src, nil
+}
+
+// component schema as a named type
+func (g *generator) component(name string, schema any) {
+	typeName := g.types[name]
+	m, _ := schema.(map[string]any)
+	description, _ := m["description"].(string)
+	doc := comment(typeName, description, "from #/components/schemas/"+name)
+	if isObject(m) {
+		g.declareStruct(typeName, m, doc)
+		return
+	}
+	g.named[typeName] = true
+	i := g.reserve()
+	g.decls[i] = fmt.Sprintf("%stype %s %s\n", doc, typeName, g.goType(schema, typeName+"Item", "item of "+typeName))
+}
+
+// reserve a declaration so that a type is declared before the types nested in it
+func (g *generator) reserve() int {
+	g.decls = append(g.decls, "")
+	return len(g.decls) - 1
+}
+
+// goType of a schema, inline objects are declared as name
+func (g *generator) goType(schema any, name, description string) string {
+	m, _ := schema.(map[string]any)
+	if ref, ok := m["$ref"].(string); ok {
+		if component, ok := strings.CutPrefix(ref, "#/components/schemas/"); ok && g.types[component] != "" {
+			return g.types[component]
+		}
+		return g.goType(g.doc.Resolve(schema), name, description)
+	}
+	if isObject(m) {
+		g.declareStruct(name, m, comment(name, description, ""))
+		return name
+	}
+	if _, ok := m["oneOf"]; ok {
+		return "any"
+	}
+	if _, ok := m["anyOf"]; ok {
+		return "any"
+	}
+	switch schemaType(m) {
+	case "string":
+		return "string"
+	case "integer":
+		if m["format"] == "int32" {
+			return "int32"
+		}
+		return "int64"
+	case "number":
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "array":
+		return "[]" + g.goType(m["items"], name+"Item", "item of "+name)
+	case "object":
+		if additional, ok := m["additionalProperties"].(map[string]any); ok {
+			return "map[string]" + g.goType(additional, name+"Value", "value of "+name)
+		}
+		return "map[string]any"
+	}
+	return "any"
+}
+
+func (g *generator) declareStruct(name string, m map[string]any, doc string) {
+	if g.named[name] {
+		return
+	}
+	g.named[name] = true
+	i := g.reserve()
+	if _, ok := m["allOf"]; ok {
+		m = g.merge(m)
+	}
+	properties, _ := m["properties"].(map[string]any)
+	required := map[string]bool{}
+	list, _ := m["required"].([]any)
+	for _, r := range list {
+		required[fmt.Sprint(r)] = true
+	}
+	var sb strings.Builder
+	_, _ = fmt.Fprintf(&sb, "%stype %s struct {\n", doc, name)
+	fields := map[string]bool{}
+	for _, p := range sortedKeys(properties) {
+		field := unique(fields, goName(p))
+		t := g.goType(properties[p], name+field, fmt.Sprintf("of %s.%s", name, p))
+		resolved, _ := g.doc.Resolve(properties[p]).(map[string]any)
+		// a readOnly property is never sent and a nullable one may be null
+		optional := !required[p] || resolved["readOnly"] == true || resolved["nullable"] == true
+		tag := p
+		if optional {
+			tag += ",omitempty"
+			if !strings.HasPrefix(t, "[]") && !strings.HasPrefix(t, "map[") && t != "any" {
+				t = "*" + t
+			}
+		}
+		_, _ = fmt.Fprintf(&sb, "%s %s `json:%q`\n", field, t, tag)
+	}
+	sb.WriteString("}\n")
+	g.decls[i] = sb.String()
+}
+
+// merge the properties and required lists of allOf, and of the schema itself
+func (g *generator) merge(m map[string]any) map[string]any {
+	properties := map[string]any{}
+	var required []any
+	var add func(schema any)
+	add = func(schema any) {
+		s, _ := g.doc.Resolve(schema).(map[string]any)
+		for k, v := range mapOf(s["properties"]) {
+			properties[k] = v
+		}
+		list, _ := s["required"].([]any)
+		required = append(required, list...)
+		all, _ := s["allOf"].([]any)
+		for _, sub := range all {
+			add(sub)
+		}
+	}
+	add(m)
+	return map[string]any{"type": "object", "properties": properties, "required": required}
+}
+
+// parameter of an operation as a constructor argument or a Params field
+type parameter struct {
+	name   string
+	ident  string
+	goType string
+	node   map[string]any
+}
+
+// operation as a Params type, a call type, a constructor and Expect methods
+func (g *generator) operation(op *openapi.Operation) string {
+	name := unique(g.operations, operationName(op))
+	call := name + "Call"
+	var sb strings.Builder
+	w := func(format string, args ...any) { _, _ = fmt.Fprintf(&sb, format, args...) }
+
+	byName := map[string]map[string]any{}
+	var query, headers []parameter
+	fields := map[string]bool{}
+	for _, p := range op.Parameters() {
+		pname, _ := p["name"].(string)
+		switch p["in"] {
+		case "path":
+			byName[pname] = p
+		case "query":
+			query = append(query, g.parameter(name+"Params", p, fields))
+		case "header":
+			switch strings.ToLower(pname) {
+			case "accept", "content-type", "authorization":
+				continue
+			}
+			headers = append(headers, g.parameter(name+"Params", p, fields))
+		}
+	}
+	sortParameters(query)
+	sortParameters(headers)
+
+	pattern := op.Path
+	var path []parameter
+	idents := map[string]bool{}
+	for i, m := range rxTemplate.FindAllStringSubmatch(op.Path, -1) {
+		p := parameter{name: m[1], ident: unique(idents, argName(m[1])), goType: "string", node: byName[m[1]]}
+		if p.node != nil {
+			p.goType = g.goType(p.node["schema"], name+goName(m[1]), "path parameter "+m[1])
+		}
+		path = append(path, p)
+		pattern = strings.Replace(pattern, m[0], "$"+strconv.Itoa(i), 1)
+	}
+
+	hasParams := len(query)+len(headers) > 0
+	if hasParams {
+		w("// %sParams query and header parameters of %s\n", name, op)
+		w("type %sParams struct {\n", name)
+		for _, p := range query {
+			tag := p.name
+			if p.node["required"] != true {
+				tag += ",omitempty"
+			}
+			w("%s %s `url:%q`\n", p.ident, p.goType, tag)
+		}
+		for _, p := range headers {
+			w("%s %s `url:\"-\"`\n", p.ident, p.goType)
+		}
+		w("}\n\n")
+	}
+
+	bodyArg, bodyCode := g.requestBody(name, op)
+
+	w("// %s %s, send it with an Expect method\n", call, op)
+	w("type %s struct {\n", call)
+	w("// Client building the request, add headers or variables before sending\n")
+	w("Client *httptestclient.Client\n")
+	w("target httptestclient.Target\n")
+	w("}\n\n")
+
+	args := []string{"t httptestclient.TestingT", "target httptestclient.Target"}
+	urlArgs := []string{strconv.Quote(pattern)}
+	for _, p := range path {
+		args = append(args, p.ident+" "+p.goType)
+		urlArgs = append(urlArgs, p.ident)
+	}
+	if hasParams {
+		args = append(args, "params "+name+"Params")
+	}
+	if bodyArg != "" {
+		args = append(args, bodyArg)
+	}
+	summary, _ := op.Node()["summary"].(string)
+	if summary != "" {
+		w("%s//\n// %s\n", comment(name, lowerFirst(summary), ""), op)
+	} else {
+		w("// %s %s\n", name, op)
+	}
+	w("func %s(%s) *%s {\n", name, strings.Join(args, ", "), call)
+	w("c := httptestclient.New(t).Method(%s).URL(%s)\n", methodConstant(op.Method), strings.Join(urlArgs, ", "))
+	if len(query) > 0 {
+		w("c.QueryStruct(params)\n")
+	}
+	for _, p := range headers {
+		w("%s", g.headerCode(p))
+	}
+	w("%s", bodyCode)
+	w("return &%s{Client: c, target: target}\n}\n", call)
+
+	for _, status := range op.Responses() {
+		code, err := strconv.Atoi(status)
+		if err != nil || len(status) != 3 {
+			continue
+		}
+		w("\n%s", g.expect(name, call, op, status, code))
+	}
+	w("\n// Expect sends the request, the response status must be status, redirects are not followed\n")
+	w("func (call *%s) Expect(status int) httptestclient.SimpleResponse {\n", call)
+	w("if status >= 300 && status < 400 {\ncall.Client.NoFollowRedirects()\n}\n")
+	w("return call.Client.ExpectedStatusCode(status).DoSimpleTarget(call.target)\n}\n")
+	return sb.String()
+}
+
+// parameter as a field of typeName, fields are the names already used
+func (g *generator) parameter(typeName string, p map[string]any, fields map[string]bool) parameter {
+	pname, _ := p["name"].(string)
+	field := unique(fields, goName(pname))
+	t := g.goType(p["schema"], typeName+field, "of "+typeName+"."+field)
+	if p["required"] != true && !strings.HasPrefix(t, "[]") && !strings.HasPrefix(t, "map[") && t != "any" {
+		t = "*" + t
+	}
+	return parameter{name: pname, ident: field, goType: t, node: p}
+}
+
+// headerCode sets the header from the Params field, optional headers only when set
+func (g *generator) headerCode(p parameter) string {
+	value := "params." + p.ident
+	switch {
+	case strings.HasPrefix(p.goType, "*"):
+		v := "*" + value
+		if p.goType != "*string" {
+			g.usesFmt = true
+			v = "fmt.Sprint(" + v + ")"
+		}
+		return fmt.Sprintf("if %s != nil {\nc.Header(%q, %s)\n}\n", value, p.name, v)
+	case strings.HasPrefix(p.goType, "[]"):
+		g.usesFmt = true
+		return fmt.Sprintf("for _, v := range %s {\nc.Header(%q, fmt.Sprint(v))\n}\n", value, p.name)
+	case p.goType == "string":
+		return fmt.Sprintf("c.Header(%q, %s)\n", p.name, value)
+	}
+	g.usesFmt = true
+	return fmt.Sprintf("c.Header(%q, fmt.Sprint(%s))\n", p.name, value)
+}
+
+// requestBody argument and the code to send it, json bodies are typed, other media types are bytes
+func (g *generator) requestBody(name string, op *openapi.Operation) (string, string) {
+	requestBody, _ := g.doc.Resolve(op.Node()["requestBody"]).(map[string]any)
+	content, _ := requestBody["content"].(map[string]any)
+	if len(content) == 0 {
+		return "", ""
+	}
+	mediaType, schema := jsonContent(content)
+	if schema == nil {
+		mediaType = sortedKeys(content)[0]
+		return "body []byte", fmt.Sprintf("c.Header(\"Content-Type\", %q).BodyBytes(body)\n", mediaType)
+	}
+	t := g.goType(schema, name+"Request", "body of "+op.String())
+	contentType := ""
+	if mediaType != "application/json" {
+		contentType = fmt.Sprintf("c.Header(\"Content-Type\", %q)\n", mediaType)
+	}
+	if requestBody["required"] == true {
+		return "body " + t, contentType + "c.BodyJSON(body)\n"
+	}
+	return "body *" + t, fmt.Sprintf("if body != nil {\n%sc.BodyJSON(body)\n}\n", contentType)
+}
+
+// expect method for a documented status, json responses are decoded
+func (g *generator) expect(name, call string, op *openapi.Operation, status string, code int) string {
+	responses, _ := op.Node()["responses"].(map[string]any)
+	response, _ := g.doc.Resolve(responses[status]).(map[string]any)
+	content, _ := response["content"].(map[string]any)
+	_, schema := jsonContent(content)
+	send := "call.Client"
+	if code >= 300 && code < 400 {
+		send += ".NoFollowRedirects()"
+	}
+	send += fmt.Sprintf(".ExpectedStatusCode(%d).DoSimpleTarget(call.target)", code)
+	if schema == nil {
+		return fmt.Sprintf("// Expect%s sends the request, the response status must be %s\n"+
+			"func (call *%s) Expect%s() httptestclient.SimpleResponse {\nreturn %s\n}\n", status, status, call, status, send)
+	}
+	t := g.goType(schema, fmt.Sprintf("%sResponse%s", name, status), fmt.Sprintf("%s body of %s", status, op))
+	return fmt.Sprintf("// Expect%s sends the request, the response status must be %s and the body is decoded\n"+
+		"func (call *%s) Expect%s() (%s, httptestclient.SimpleResponse) {\n"+
+		"resp := %s\n"+
+		"var body %s\n"+
+		"if resp.Response != nil {\nresp.BodyJSON(&body)\n}\n"+
+		"return body, resp\n}\n", status, status, call, status, t, send, t)
+}
+
+// jsonContent media type and schema, application/json is preferred over other json media types
+func jsonContent(content map[string]any) (string, any) {
+	keys := sortedKeys(content)
+	sort.SliceStable(keys, func(i, j int) bool { return keys[i] == "application/json" && keys[j] != "application/json" })
+	for _, k := range keys {
+		if k != "application/json" && !strings.HasSuffix(k, "+json") {
+			continue
+		}
+		m, _ := content[k].(map[string]any)
+		if schema, ok := m["schema"]; ok {
+			return k, schema
+		}
+	}
+	return "", nil
+}
+
+var rxTemplate = regexp.MustCompile(`\{([^}/]+)\}`)
+
+// operationName from the operationId, or the method and path, GET /items/{id} is GetItemsByID
+func operationName(op *openapi.Operation) string {
+	if op.ID != "" {
+		return goName(op.ID)
+	}
+	name := goName(strings.ToLower(op.Method))
+	for _, segment := range strings.Split(op.Path, "/") {
+		if segment == "" {
+			continue
+		}
+		if m := rxTemplate.FindStringSubmatch(segment); m != nil {
+			name += "By" + goName(m[1])
+			continue
+		}
+		name += goName(segment)
+	}
+	return name
+}
+
+func methodConstant(method string) string {
+	switch method {
+	case "GET", "PUT", "POST", "DELETE", "OPTIONS", "HEAD", "PATCH", "TRACE":
+		return "http.Method" + goName(strings.ToLower(method))
+	}
+	return strconv.Quote(method)
+}
+
+var rxSeparator = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// goName exported Go name, "customer_id", "customerId" and "Customer-ID" are all CustomerID
+func goName(s string) string {
+	var sb strings.Builder
+	for _, word := range words(s) {
+		if upper := strings.ToUpper(word); initialisms[upper] {
+			sb.WriteString(upper)
+			continue
+		}
+		sb.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	name := sb.String()
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		name = "N" + name
+	}
+	return name
+}
+
+// unique name, when it is already used a number is added, e.g. CustomerID2, used is updated
+func unique(used map[string]bool, name string) string {
+	candidate := name
+	for i := 2; used[candidate]; i++ {
+		candidate = name + strconv.Itoa(i)
+	}
+	used[candidate] = true
+	return candidate
+}
+
+// words split at separators and where lower case is followed by upper case
+func words(s string) []string {
+	var result []string
+	for _, part := range rxSeparator.Split(s, -1) {
+		start := 0
+		for i := 1; i < len(part); i++ {
+			if unicode.IsUpper(rune(part[i])) && !unicode.IsUpper(rune(part[i-1])) {
+				result = append(result, part[start:i])
+				start = i
+			}
+		}
+		if start < len(part) {
+			result = append(result, part[start:])
+		}
+	}
+	return result
+}
+
+// argName unexported Go name that is not a keyword nor a reserved argument
+func argName(s string) string {
+	name := goName(s)
+	for i, r := range name {
+		if !unicode.IsUpper(r) {
+			if i > 1 {
+				// IDValue is idValue
+				i--
+			}
+			name = strings.ToLower(name[:i]) + name[i:]
+			break
+		}
+		if i == len(name)-1 {
+			name = strings.ToLower(name)
+		}
+	}
+	if token.IsKeyword(name) || reserved[name] {
+		name += "Arg"
+	}
+	return name
+}
+
+// comment for a declaration, description is used when it starts with the name
+func comment(name, description, fallback string) string {
+	description = strings.TrimSpace(strings.SplitN(description, "\n", 2)[0])
+	switch {
+	case description == "":
+		return strings.TrimRight("// "+name+" "+fallback, " ") + "\n"
+	case strings.HasPrefix(description, name+" "):
+		return "// " + description + "\n"
+	}
+	return "// " + name + " " + description + "\n"
+}
+
+// lowerFirst "List customers" is "list customers", "URL of" is unchanged
+func lowerFirst(s string) string {
+	if len(s) > 1 && unicode.IsUpper(rune(s[0])) && !unicode.IsUpper(rune(s[1])) {
+		return strings.ToLower(s[:1]) + s[1:]
+	}
+	return s
+}
+
+func isObject(m map[string]any) bool {
+	if _, ok := m["allOf"]; ok {
+		return true
+	}
+	_, ok := m["properties"]
+	return ok && (m["type"] == nil || m["type"] == "object")
+}
+
+// schemaType the first type that is not null, OpenAPI 3.1 allows a list
+func schemaType(m map[string]any) string {
+	switch t := m["type"].(type) {
+	case string:
+		return t
+	case []any:
+		for _, v := range t {
+			if v != "null" {
+				return fmt.Sprint(v)
+			}
+		}
+	}
+	return ""
+}
+
+func sortParameters(params []parameter) {
+	sort.Slice(params, func(i, j int) bool { return params[i].ident < params[j].ident })
+}
+
+func mapOf(v any) map[string]any {
+	m, _ := v.(map[string]any)
+	return m
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_generated_clients_match_golden_files(t *testing.T) {
+	testData := []struct {
+		golden string
+		input  string
+	}{
+		{"customers.golden", "testdata/customers.yaml"},
+		{"collisions.golden", "testdata/collisions.yaml"},
+	}
+	for _, td := range testData {
+		t.Run(td.golden, func(t *testing.T) {
+			out := filepath.Join(t.TempDir(), "client.go")
+
+			require.NoError(t, run([]string{"-out", out, td.input}))
+
+			expected, err := os.ReadFile(filepath.Join("testdata", td.golden))
+			require.NoError(t, err)
+			actual, err := os.ReadFile(out)
+			require.NoError(t, err)
+			assert.Equal(t, string(expected), string(actual))
+		})
+	}
+}
+
+func Test_generated_clients_compile(t *testing.T) {
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go is not on the path")
+	}
+	root, err := filepath.Abs("../..")
+	require.NoError(t, err)
+	goSum, err := os.ReadFile(filepath.Join(root, "go.sum"))
+	require.NoError(t, err)
+	// a module that uses this one, each generated client is a package compiled by vet
+	dir := t.TempDir()
+	goMod := "module generated\n\ngo 1.25\n\nrequire github.com/NearlyUnique/httptestclient v0.0.0\n\n" +
+		"replace github.com/NearlyUnique/httptestclient => " + root + "\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte(goMod), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.sum"), goSum, 0o644))
+	for _, pkg := range []string{"customers", "collisions"} {
+		require.NoError(t, os.Mkdir(filepath.Join(dir, pkg), 0o755))
+		out := filepath.Join(dir, pkg, "client.go")
+		require.NoError(t, run([]string{"-package", pkg, "-out", out, filepath.Join("testdata", pkg+".yaml")}))
+	}
+
+	cmd := exec.Command(goTool, "vet", "./...")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off")
+	output, err := cmd.CombinedOutput()
+
+	assert.NoError(t, err, string(output))
+}
+
+func Test_generator_errors(t *testing.T) {
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "empty.yaml")
+	require.NoError(t, os.WriteFile(empty, []byte("openapi: 3.0.0\npaths: {}\n"), 0o644))
+
+	testData := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"no input", nil, "one openapi document is required"},
+		{"missing file", []string{"testdata/missing.yaml"}, "open testdata/missing.yaml: no such file or directory"},
+		{"no operations", []string{empty}, "no operations to generate"},
+	}
+	for _, td := range testData {
+		t.Run(td.name, func(t *testing.T) {
+			err := run(append([]string{"-out", filepath.Join(dir, "client.go")}, td.args...))
+
+			assert.EqualError(t, err, td.expected)
+		})
+	}
+}
+
+func Test_go_names(t *testing.T) {
+	testData := []struct {
+		input, name, arg string
+	}{
+		{"customerId", "CustomerID", "customerID"},
+		{"customer_id", "CustomerID", "customerID"},
+		{"X-Request-Id", "XRequestID", "xRequestID"},
+		{"id", "ID", "id"},
+		{"URLPath", "URLPath", "urlPath"},
+		{"2fa", "N2fa", "n2fa"},
+		{"type", "Type", "typeArg"},
+		{"target", "Target", "targetArg"},
+	}
+	for _, td := range testData {
+		t.Run(td.input, func(t *testing.T) {
+			assert.Equal(t, td.name, goName(td.input))
+			assert.Equal(t, td.arg, argName(td.input))
+		})
+	}
+}
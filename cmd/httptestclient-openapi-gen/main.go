@@ -0,0 +1,56 @@
+// Command httptestclient-openapi-gen generates a typed wrapper, built on httptestclient.Client, for every operation
+// of an OpenAPI 3 document, with structs for the request and response bodies
+//
+//	httptestclient-openapi-gen -package api -out api/client.go openapi.yaml
+//
+// then in a test
+//
+//	customer, _ := api.CreateCustomer(t, httptestclient.Server(srv), api.Customer{Name: "Ada"}).Expect201()
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/NearlyUnique/httptestclient/internal/openapi"
+)
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "httptestclient-openapi-gen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	fs := flag.NewFlagSet("httptestclient-openapi-gen", flag.ContinueOnError)
+	out := fs.String("out", "", "output file, default stdout")
+	pkg := fs.String("package", "api", "package of the generated code")
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "usage: httptestclient-openapi-gen [flags] openapi.yaml|openapi.json\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("one openapi document is required")
+	}
+	in := fs.Arg(0)
+	doc, err := openapi.Load(in)
+	if err != nil {
+		return err
+	}
+	src, err := generate(doc, *pkg, filepath.Base(in))
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return os.WriteFile(*out, src, 0o644)
+}
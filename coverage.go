@@ -0,0 +1,180 @@
+package httptestclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/NearlyUnique/httptestclient/internal/openapi"
+)
+
+// covering documents that record the traffic of every Client, see CoverOpenAPI
+var covering struct {
+	mu   sync.Mutex
+	docs []*OpenAPI
+}
+
+// CoverOpenAPI records the responses received by every Client in the package against doc, whether doc is attached
+// or not, call from TestMain before m.Run then write the report with WriteCoverage or WriteCoverageJSON
+//
+//	func TestMain(m *testing.M) {
+//		doc, _ := httptestclient.LoadOpenAPI("openapi.yaml")
+//		httptestclient.CoverOpenAPI(doc)
+//		code := m.Run()
+//		_ = doc.WriteCoverage(os.Stdout)
+//		os.Exit(code)
+//	}
+func CoverOpenAPI(doc *OpenAPI) {
+	covering.mu.Lock()
+	defer covering.mu.Unlock()
+	if !slices.Contains(covering.docs, doc) {
+		covering.docs = append(covering.docs, doc)
+	}
+}
+
+// OpenAPICoverage of the operations in a document by the responses received
+type OpenAPICoverage struct {
+	TotalOperations   int                 `json:"total_operations"`
+	CoveredOperations int                 `json:"covered_operations"`
+	TotalResponses    int                 `json:"total_responses"`
+	CoveredResponses  int                 `json:"covered_responses"`
+	Operations        []OperationCoverage `json:"operations"`
+}
+
+// OperationCoverage by status, Undocumented are statuses received that the operation does not document
+type OperationCoverage struct {
+	Method       string             `json:"method"`
+	Path         string             `json:"path"`
+	OperationID  string             `json:"operation_id,omitempty"`
+	Requests     int                `json:"requests"`
+	Responses    []ResponseCoverage `json:"responses"`
+	Undocumented []int              `json:"undocumented,omitempty"`
+}
+
+// ResponseCoverage of a documented response, Status as documented, e.g. "200", "4XX" or "default"
+type ResponseCoverage struct {
+	Status   string `json:"status"`
+	Received int    `json:"received"`
+}
+
+// cover the exchange, every response including redirects, in the attached and the covering documents
+func cover(attached *OpenAPI, baseURL string, e *exchange) {
+	covering.mu.Lock()
+	docs := slices.Clone(covering.docs)
+	covering.mu.Unlock()
+	if attached != nil && !slices.Contains(docs, attached) {
+		docs = append(docs, attached)
+	}
+	responses := []*http.Response{}
+	for _, hop := range e.hops {
+		responses = append(responses, hop.response)
+	}
+	if e.response != nil {
+		responses = append(responses, e.response)
+	}
+	for _, doc := range docs {
+		for _, resp := range responses {
+			doc.received(baseURL, resp)
+		}
+	}
+}
+
+func (o *OpenAPI) received(baseURL string, resp *http.Response) {
+	op, _, _ := o.doc.Find(resp.Request.Method, relativePath(baseURL, resp.Request.URL))
+	if op == nil {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.responses == nil {
+		o.responses = map[*openapi.Operation]map[int]int{}
+	}
+	if o.responses[op] == nil {
+		o.responses[op] = map[int]int{}
+	}
+	o.responses[op][resp.StatusCode]++
+}
+
+// Coverage of the document by the responses received so far
+func (o *OpenAPI) Coverage() OpenAPICoverage {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var result OpenAPICoverage
+	for _, op := range o.doc.Operations() {
+		oc := OperationCoverage{Method: op.Method, Path: op.Path, OperationID: op.ID, Responses: []ResponseCoverage{}}
+		documented := map[string]int{}
+		for status, n := range o.responses[op] {
+			oc.Requests += n
+			if key, ok := op.ResponseKey(status); ok {
+				documented[key] += n
+			} else {
+				oc.Undocumented = append(oc.Undocumented, status)
+			}
+		}
+		slices.Sort(oc.Undocumented)
+		for _, key := range op.Responses() {
+			oc.Responses = append(oc.Responses, ResponseCoverage{Status: key, Received: documented[key]})
+			result.TotalResponses++
+			if documented[key] > 0 {
+				result.CoveredResponses++
+			}
+		}
+		result.TotalOperations++
+		if oc.Requests > 0 {
+			result.CoveredOperations++
+		}
+		result.Operations = append(result.Operations, oc)
+	}
+	return result
+}
+
+// WriteCoverage as text, listing the untested operations, the untested documented responses and any undocumented
+// responses received
+func (o *OpenAPI) WriteCoverage(w io.Writer) error {
+	c := o.Coverage()
+	var untestedOps, untestedResponses, undocumented []string
+	for _, oc := range c.Operations {
+		name := oc.Method + " " + oc.Path
+		if oc.Requests == 0 {
+			untestedOps = append(untestedOps, name)
+		}
+		for _, r := range oc.Responses {
+			if r.Received == 0 {
+				untestedResponses = append(untestedResponses, name+" "+r.Status)
+			}
+		}
+		for _, status := range oc.Undocumented {
+			undocumented = append(undocumented, name+" "+strconv.Itoa(status))
+		}
+	}
+	var sb strings.Builder
+	_, _ = fmt.Fprintf(&sb, "openapi coverage: %d of %d operations, %d of %d responses\n",
+		c.CoveredOperations, c.TotalOperations, c.CoveredResponses, c.TotalResponses)
+	for _, section := range []struct {
+		title string
+		lines []string
+	}{
+		{"untested operations", untestedOps},
+		{"untested responses", untestedResponses},
+		{"undocumented responses", undocumented},
+	} {
+		if len(section.lines) == 0 {
+			continue
+		}
+		_, _ = fmt.Fprintf(&sb, "\n%s\n  %s\n", section.title, strings.Join(section.lines, "\n  "))
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// WriteCoverageJSON as an indented OpenAPICoverage
+func (o *OpenAPI) WriteCoverageJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(o.Coverage())
+}
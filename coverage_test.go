@@ -0,0 +1,88 @@
+package httptestclient_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/NearlyUnique/httptestclient"
+	"github.com/NearlyUnique/httptestclient/internal/self"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_openapi_coverage_lists_untested_operations_and_responses(t *testing.T) {
+	doc := loadItemsOpenAPI(t)
+	session := httptestclient.NewSession(httptestclient.Handler(openAPIServer(new(int32)))).OpenAPI(doc)
+
+	httptestclient.New(t).Post("/api/items").BodyString(`{"name":"widget"}`).ExpectedStatusCode(http.StatusCreated).DoSimpleTarget(session)
+	httptestclient.New(t).Get("/api/items").DoSimpleTarget(session)
+	httptestclient.New(t).Get("/api/items").DoSimpleTarget(session)
+	httptestclient.New(t).Get("/api/items/$0", 1).DoSimpleTarget(session)
+	httptestclient.New(t).Get("/api/items/$0", 9).ExpectedStatusCode(http.StatusNotFound).DoSimpleTarget(session)
+	// received and covered even though the test fails
+	httptestclient.New(self.NewFakeTester(func(format string, args ...interface{}) {})).
+		Get("/api/items/$0", 3).
+		ExpectedStatusCode(http.StatusInternalServerError).
+		DoSimpleTarget(session)
+
+	var buf bytes.Buffer
+	require.NoError(t, doc.WriteCoverage(&buf))
+
+	assert.Equal(t, `openapi coverage: 3 of 5 operations, 4 of 6 responses
+
+untested operations
+  GET /items/mine
+  DELETE /items/{id}
+
+untested responses
+  GET /items/mine 200
+  DELETE /items/{id} 204
+
+undocumented responses
+  GET /items/{id} 500
+`, buf.String())
+
+	coverage := doc.Coverage()
+	require.Len(t, coverage.Operations, 5)
+	assert.Equal(t, httptestclient.OperationCoverage{
+		Method:      http.MethodGet,
+		Path:        "/items",
+		OperationID: "listItems",
+		Requests:    2,
+		Responses:   []httptestclient.ResponseCoverage{{Status: "200", Received: 2}},
+	}, coverage.Operations[0])
+}
+
+func Test_CoverOpenAPI_records_clients_without_the_document(t *testing.T) {
+	doc := loadItemsOpenAPI(t)
+	httptestclient.CoverOpenAPI(doc)
+
+	httptestclient.New(t).Delete("/api/items/$0", 1).ExpectedStatusCode(http.StatusNoContent).
+		DoSimpleTarget(httptestclient.Handler(openAPIServer(new(int32))))
+
+	var buf bytes.Buffer
+	require.NoError(t, doc.WriteCoverageJSON(&buf))
+	var actual struct {
+		CoveredOperations int `json:"covered_operations"`
+		TotalResponses    int `json:"total_responses"`
+		Operations        []struct {
+			Path      string `json:"path"`
+			Requests  int    `json:"requests"`
+			Responses []struct {
+				Status   string `json:"status"`
+				Received int    `json:"received"`
+			} `json:"responses"`
+		} `json:"operations"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &actual))
+
+	assert.Equal(t, 1, actual.CoveredOperations)
+	assert.Equal(t, 6, actual.TotalResponses)
+	require.Len(t, actual.Operations, 5)
+	assert.Equal(t, "/items/{id}", actual.Operations[3].Path)
+	assert.Equal(t, 1, actual.Operations[3].Requests)
+	assert.Equal(t, "204", actual.Operations[3].Responses[0].Status)
+	assert.Equal(t, 1, actual.Operations[3].Responses[0].Received)
+}
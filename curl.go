@@ -0,0 +1,87 @@
+package httptestclient
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Curl command equivalent to the last request sent, every argument is shell quoted. The headers include any
+// cookies sent by the session. Requests to a Handler target are relative to "$BASE_URL", set it to wherever the
+// handler is served. A password in the URL is not included, curl prompts for it. Calling Curl before a request
+// has been sent fails the test
+func (c *Client) Curl() string {
+	if h, ok := c.t.(testingHooks); ok {
+		h.Helper()
+	}
+	if c.err != nil {
+		return ""
+	}
+	if len(c.transcript) == 0 {
+		c.failNow("Curl requires a request to have been sent")
+		return ""
+	}
+	return c.transcript[len(c.transcript)-1].curl()
+}
+
+// CurlOnFailure adds the curl command for the request to the failure message, when the request has been sent
+func (c *Client) CurlOnFailure() *Client {
+	c.curlOnFailure = true
+	return c
+}
+
+// curl for the request as it was sent
+func (e *exchange) curl() string {
+	req := e.request
+	args := []string{"curl"}
+	switch {
+	case req.Method == http.MethodHead:
+		// -X HEAD waits for a body that never arrives
+		args = append(args, "-I")
+	case req.Method != http.MethodGet || e.requestBody != nil:
+		args = append(args, "-X", req.Method)
+	}
+	if e.inProcess {
+		args = append(args, `"$BASE_URL"`+shellQuote(req.URL.RequestURI()))
+	} else {
+		u := *req.URL
+		u.User = nil
+		args = append(args, shellQuote(u.String()))
+		if req.URL.User != nil {
+			// curl prompts for the password
+			args = append(args, "-u", shellQuote(req.URL.User.Username()))
+		}
+	}
+	for _, k := range sortedKeys(req.Header) {
+		if len(e.multipart) > 0 && k == "Content-Type" {
+			// curl sets the boundary
+			continue
+		}
+		for _, v := range req.Header[k] {
+			if v == "" {
+				// "X-Empty:" would remove the header
+				args = append(args, "-H", shellQuote(k+";"))
+				continue
+			}
+			args = append(args, "-H", shellQuote(k+": "+v))
+		}
+	}
+	switch {
+	case len(e.multipart) > 0:
+		for _, p := range e.multipart {
+			if p.filename == "" {
+				args = append(args, "-F", shellQuote(p.field+"="+string(p.content)))
+				continue
+			}
+			// the file must exist where curl is run
+			args = append(args, "-F", shellQuote(p.field+"=@"+p.filename+";type="+p.contentType))
+		}
+	case e.requestBody != nil:
+		args = append(args, "--data-raw", shellQuote(string(e.requestBody)))
+	}
+	return strings.Join(args, " ")
+}
+
+// shellQuote for a POSIX shell, single quotes are closed, escaped and reopened
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
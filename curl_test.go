@@ -0,0 +1,130 @@
+package httptestclient_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/NearlyUnique/httptestclient"
+	"github.com/NearlyUnique/httptestclient/internal/self"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_curl_commands(t *testing.T) {
+	target := httptestclient.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	testData := []struct {
+		name     string
+		client   func(t *testing.T) *httptestclient.Client
+		expected string
+	}{
+		{
+			"get",
+			func(t *testing.T) *httptestclient.Client {
+				return httptestclient.New(t).Get("/items/$0", "a b").Query("q", "x")
+			},
+			`curl "$BASE_URL"'/items/a%20b?q=x' -H 'Accept: application/json' -H 'User-Agent: test-http-request'`,
+		},
+		{
+			"head",
+			func(t *testing.T) *httptestclient.Client {
+				return httptestclient.New(t).ClearHeaders().Method(http.MethodHead).URL("/items")
+			},
+			`curl -I "$BASE_URL"'/items'`,
+		},
+		{
+			"body is quoted",
+			func(t *testing.T) *httptestclient.Client {
+				return httptestclient.New(t).ClearHeaders().Put("/items").BodyString(`{"name":"it's"}`)
+			},
+			`curl -X PUT "$BASE_URL"'/items' -H 'Content-Type: application/json' --data-raw '{"name":"it'\''s"}'`,
+		},
+		{
+			"form",
+			func(t *testing.T) *httptestclient.Client {
+				return httptestclient.New(t).ClearHeaders().Post("/login").FormData("user", "bob", "pass", "a&b")
+			},
+			`curl -X POST "$BASE_URL"'/login' -H 'Content-Type: application/x-www-form-urlencoded' --data-raw 'pass=a%26b&user=bob'`,
+		},
+		{
+			"multipart",
+			func(t *testing.T) *httptestclient.Client {
+				return httptestclient.New(t).ClearHeaders().Post("/upload").
+					MultipartField("title", "hi").
+					MultipartFile("photo", "me.png", strings.NewReader("png"))
+			},
+			`curl -X POST "$BASE_URL"'/upload' -F 'title=hi' -F 'photo=@me.png;type=image/png'`,
+		},
+		{
+			"variables",
+			func(t *testing.T) *httptestclient.Client {
+				return httptestclient.New(t).ClearHeaders().Var("token", "t-1").Header("Authorization", "Bearer ${token}").Delete("/items/1")
+			},
+			`curl -X DELETE "$BASE_URL"'/items/1' -H 'Authorization: Bearer t-1'`,
+		},
+		{
+			"empty header",
+			func(t *testing.T) *httptestclient.Client {
+				return httptestclient.New(t).ClearHeaders().Header("X-Empty", "").Get("/items")
+			},
+			`curl "$BASE_URL"'/items' -H 'X-Empty;'`,
+		},
+	}
+	for _, td := range testData {
+		t.Run(td.name, func(t *testing.T) {
+			c := td.client(t)
+			_ = c.DoTarget(target)
+			assert.Equal(t, td.expected, c.Curl())
+		})
+	}
+}
+
+func Test_curl_is_the_request_that_was_sent(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "s-1"})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"next":"2"}`))
+	}))
+	defer s.Close()
+
+	t.Run("with the server url and cookies", func(t *testing.T) {
+		c := httptestclient.New(t).ClearHeaders()
+		_ = c.Get("/login").DoSimple(s)
+		_ = c.Get("/profile").DoSimple(s)
+
+		assert.Equal(t, `curl '`+s.URL+`/profile' -H 'Cookie: session=s-1'`, c.Curl())
+	})
+	t.Run("before a captured variable changed", func(t *testing.T) {
+		c := httptestclient.New(t).ClearHeaders().Var("id", "1").Get("/items/${id}").CaptureVar("id", "$.next")
+		_ = c.DoSimple(s)
+
+		assert.Equal(t, `curl '`+s.URL+`/items/1'`, c.Curl())
+	})
+	t.Run("fails before a request is sent", func(t *testing.T) {
+		var messages []string
+		c := httptestclient.New(self.NewFakeTester(func(format string, args ...interface{}) {
+			messages = append(messages, format)
+		})).Get("/items")
+
+		assert.Empty(t, c.Curl())
+		assert.Equal(t, []string{"Curl requires a request to have been sent"}, messages)
+	})
+}
+
+func Test_curl_on_failure(t *testing.T) {
+	target := httptestclient.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	_ = httptestclient.New(self.NewFakeTester(func(format string, args ...interface{}) {
+		assert.Equal(t, "expected success, got %d\nreproduce with: %s", format)
+		require.Equal(t, 2, len(args))
+		assert.Equal(t, `curl "$BASE_URL"'/tea' -H 'Accept: application/json' -H 'User-Agent: test-http-request'`, args[1])
+	})).
+		Get("/tea").
+		CurlOnFailure().
+		DoSimpleTarget(target)
+}
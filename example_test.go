@@ -78,6 +78,19 @@ func Test_Example(t *testing.T) {
 	}
 }
 
+func Test_Example_with_response_assertions(t *testing.T) {
+	myTestDatabase := map[string]string{"database-key": "Hello"}
+	s := httptest.NewServer(ProductionHandler(myTestDatabase))
+	defer s.Close()
+
+	httptestclient.New(t).
+		Post("/any/$0", "database-key").
+		BodyJSON(&Customer{Name: "Bob"}).
+		Header("custom", "😊").
+		DoSimple(s).
+		ExpectJSONEq(`{"value":"Hello Bob 😊"}`)
+}
+
 func Test_Example_the_long_complicated_way(t *testing.T) {
 	myTestDatabase := map[string]string{"database-key": "Hello"}
 	s := httptest.NewServer(ProductionHandler(myTestDatabase))
@@ -0,0 +1,252 @@
+package httptestclient
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TranscriptBodyLimit bodies longer than this are truncated in the transcript
+var TranscriptBodyLimit = 2048
+
+// TranscriptRedactHeaders values are replaced with "<redacted>" in the transcript
+var TranscriptRedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// exchange a single request and its response, including any redirects followed
+type exchange struct {
+	started     time.Time
+	duration    time.Duration
+	request     *http.Request
+	requestBody []byte
+	requestSize int
+	response    *http.Response
+	redirects   []RedirectHop
+	hops        []hopResponse
+	err         error
+	// keepBodies for HAR export, otherwise bodies are trimmed to TranscriptBodyLimit
+	keepBodies bool
+	// multipart parts of the request body, for curl
+	multipart []multipartPart
+	// inProcess when sent to a Handler target
+	inProcess bool
+
+	// mu guards the response body, as a streamed body is recorded while the caller reads it, and the round trips
+	// as they are traced by the transport
+	mu           sync.Mutex
+	responseBody []byte
+	responseSize int
+	roundTrips   []roundTrip
+}
+
+// roundTrip when the request, or a redirect, was written and the first byte of its response was received
+type roundTrip struct {
+	wrote     time.Time
+	firstByte time.Time
+}
+
+// trace the round trips of req, transports that do not support httptrace, e.g. Handler, are not traced
+func (e *exchange) trace(req *http.Request) *http.Request {
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			e.mu.Lock()
+			defer e.mu.Unlock()
+			e.roundTrips = append(e.roundTrips, roundTrip{wrote: time.Now()})
+		},
+		GotFirstResponseByte: func() {
+			e.mu.Lock()
+			defer e.mu.Unlock()
+			if n := len(e.roundTrips); n > 0 {
+				e.roundTrips[n-1].firstByte = time.Now()
+			}
+		},
+	}))
+}
+
+// roundTripAt index i, ok is false when it was not traced
+func (e *exchange) roundTripAt(i int) (roundTrip, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if i >= len(e.roundTrips) || e.roundTrips[i].firstByte.IsZero() {
+		return roundTrip{}, false
+	}
+	return e.roundTrips[i], true
+}
+
+// hopResponse a redirect response and when it was received
+type hopResponse struct {
+	response *http.Response
+	at       time.Time
+}
+
+// bufferBody reads the whole response body so it can be validated, captured or recorded, the caller can still read it
+func (e *exchange) bufferBody(resp *http.Response) error {
+	defer func() { _ = resp.Body.Close() }()
+	buf, err := io.ReadAll(resp.Body)
+	resp.Body = io.NopCloser(bytes.NewReader(buf))
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.responseBody, e.responseSize = buf, len(buf)
+	return err
+}
+
+// teeBody records the response body as the caller reads it, streamed responses are delivered as they arrive
+func (e *exchange) teeBody(resp *http.Response) {
+	resp.Body = &recordingBody{ReadCloser: resp.Body, e: e}
+}
+
+// recordingBody copies what is read into the exchange
+type recordingBody struct {
+	io.ReadCloser
+	e *exchange
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.e.received(p[:n])
+	return n, err
+}
+
+// received part of the response body, only the first TranscriptBodyLimit bytes are kept unless keepBodies
+func (e *exchange) received(p []byte) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.responseSize += len(p)
+	keep := len(p)
+	if !e.keepBodies {
+		keep = min(keep, TranscriptBodyLimit-len(e.responseBody))
+	}
+	if keep > 0 {
+		e.responseBody = append(e.responseBody, p[:keep]...)
+	}
+}
+
+// body of the response received so far
+func (e *exchange) body() []byte {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.responseBody
+}
+
+// drainLimit bytes read from a response that is not returned, so the transcript reports the body size
+const drainLimit = 1 << 20
+
+// drain the body, up to drainLimit, for the transcript when the response is not returned to the caller, then close it
+func (e *exchange) drain() {
+	if e.response == nil || e.response.Body == nil {
+		return
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(e.response.Body, drainLimit))
+	_ = e.response.Body.Close()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.responseSize = max(e.responseSize, int(e.response.ContentLength))
+}
+
+// trim the bodies to TranscriptBodyLimit once the exchange is no longer the latest, the request and response
+// are copied without their bodies so that the exchange no longer holds them
+func (e *exchange) trim() {
+	if e.keepBodies {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.requestBody = truncateBody(e.requestBody)
+	e.responseBody = truncateBody(e.responseBody)
+	e.request = withoutBody(e.request)
+	e.multipart = nil
+	if e.response != nil {
+		resp := *e.response
+		resp.Body, resp.Request = nil, withoutBody(resp.Request)
+		e.response = &resp
+	}
+}
+
+func truncateBody(body []byte) []byte {
+	if len(body) > TranscriptBodyLimit {
+		return bytes.Clone(body[:TranscriptBodyLimit])
+	}
+	return body
+}
+
+func withoutBody(req *http.Request) *http.Request {
+	if req == nil {
+		return nil
+	}
+	r := *req
+	r.Body, r.GetBody = nil, nil
+	return &r
+}
+
+// Transcript of every request sent and response received in HTTP/1.1 style, this is logged automatically
+// when the test fails
+func (c *Client) Transcript() string {
+	var sb strings.Builder
+	_, _ = fmt.Fprintf(&sb, "httptestclient transcript, %d request(s)\n", len(c.transcript))
+	for i, e := range c.transcript {
+		e.dump(&sb, i+1)
+	}
+	return sb.String()
+}
+
+func (e *exchange) dump(w io.Writer, n int) {
+	_, _ = fmt.Fprintf(w, "--- request %d ---\n", n)
+	_, _ = fmt.Fprintf(w, "%s %s HTTP/1.1\n", e.request.Method, e.request.URL.Redacted())
+	dumpHeader(w, e.request.Header)
+	dumpBody(w, e.requestBody, e.requestSize)
+	for _, hop := range e.redirects {
+		_, _ = fmt.Fprintf(w, "--- redirect %d %s -> %s %s%s\n", hop.Status, http.StatusText(hop.Status), hop.Method, hop.Host, hop.Path)
+	}
+	if e.err != nil {
+		_, _ = fmt.Fprintf(w, "--- error ---\n%v\n", e.err)
+		return
+	}
+	if e.response == nil {
+		return
+	}
+	_, _ = fmt.Fprintf(w, "--- response %d (%s) ---\n", n, e.duration.Round(time.Microsecond))
+	_, _ = fmt.Fprintf(w, "%s %s\n", e.response.Proto, e.response.Status)
+	dumpHeader(w, e.response.Header)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	dumpBody(w, e.responseBody, e.responseSize)
+}
+
+func dumpHeader(w io.Writer, h http.Header) {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		redact := slices.ContainsFunc(TranscriptRedactHeaders, func(name string) bool { return strings.EqualFold(name, k) })
+		for _, v := range h[k] {
+			if redact {
+				v = redacted
+			}
+			_, _ = fmt.Fprintf(w, "%s: %s\n", k, v)
+		}
+	}
+}
+
+// dumpBody of size bytes, body may already have been truncated
+func dumpBody(w io.Writer, body []byte, size int) {
+	_, _ = fmt.Fprintln(w)
+	if size == 0 {
+		return
+	}
+	if len(body) > TranscriptBodyLimit {
+		body = body[:TranscriptBodyLimit]
+	}
+	if size > len(body) {
+		_, _ = fmt.Fprintf(w, "%s\n... truncated %d of %d bytes\n", body, size-len(body), size)
+		return
+	}
+	_, _ = fmt.Fprintf(w, "%s\n", body)
+}
@@ -0,0 +1,365 @@
+package httptestclient
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// curlFlagsWithValue short and long curl options that take a value and are understood
+var curlFlagsWithValue = map[string]string{
+	"-X": "--request", "-H": "--header", "-d": "--data", "-F": "--form", "-u": "--user",
+	"-b": "--cookie", "-A": "--user-agent", "-e": "--referer",
+	"--request": "", "--header": "", "--data": "", "--data-raw": "", "--data-binary": "", "--data-ascii": "",
+	"--data-urlencode": "", "--form": "", "--form-string": "", "--user": "", "--cookie": "", "--user-agent": "",
+	"--referer": "", "--url": "",
+	// accepted and ignored
+	"-o": "--output", "-m": "--max-time", "--output": "", "--max-time": "", "--connect-timeout": "", "--retry": "",
+}
+
+// curlFlagsIgnored have no value and do not change the request, --compressed is handled by the transport
+var curlFlagsIgnored = map[string]string{
+	"-s": "--silent", "-S": "--show-error", "-v": "--verbose", "-k": "--insecure", "-L": "--location",
+	"-i": "--include", "-f": "--fail", "--compressed": "", "--silent": "", "--show-error": "", "--verbose": "",
+	"--insecure": "", "--location": "", "--include": "", "--fail": "", "--http1.1": "", "--http2": "",
+}
+
+// FromCurl a Client configured from a curl command, e.g. pasted from a bug report. The scheme and host are
+// dropped so the request can be sent to any Target. Supported options are -X, -H, -d and the --data variants,
+// --data-urlencode, -F, -u, -b, -A, -e, -G and -I, files referenced with @ are read relative to the working directory.
+// Only the headers in the command are sent, the Client defaults are cleared
+func FromCurl(t TestingT, command string) *Client {
+	if h, ok := t.(testingHooks); ok {
+		h.Helper()
+	}
+	c := New(t)
+	args, err := splitShellWords(command)
+	if c.hasError(err) {
+		return c
+	}
+	cmd, err := parseCurl(args)
+	if c.hasError(err) {
+		return c
+	}
+	c.Method(cmd.method).URL("$0", Raw(cmd.url))
+	// only the headers in the command are sent, values are literal so ${ is not expanded
+	c.ClearHeaders()
+	for _, h := range cmd.headers {
+		c.header.Add(h[0], escapeVars(h[1]))
+	}
+	for _, f := range cmd.form {
+		if f.filename == "" {
+			c.MultipartField(f.field, string(f.content))
+			continue
+		}
+		c.addMultipart(f)
+	}
+	if cmd.body != nil {
+		c.BodyBytes(cmd.body)
+	}
+	return c
+}
+
+// curlCommand the parts of a curl command that describe the request
+type curlCommand struct {
+	method  string
+	url     string
+	headers [][2]string
+	data    []string
+	get     bool
+	body    []byte
+	form    []multipartPart
+}
+
+func parseCurl(args []string) (*curlCommand, error) {
+	if len(args) > 0 && args[0] == "curl" {
+		args = args[1:]
+	}
+	cmd := &curlCommand{}
+	explicitMethod := ""
+	header := func(name, value string) {
+		cmd.headers = append(cmd.headers, [2]string{http.CanonicalHeaderKey(strings.TrimSpace(name)), strings.TrimSpace(value)})
+	}
+	for i := 0; i < len(args); i++ {
+		flag, value, hasValue := args[i], "", false
+		if !strings.HasPrefix(flag, "-") || flag == "-" {
+			if cmd.url != "" {
+				return nil, fmt.Errorf("more than one url, '%s' and '%s'", cmd.url, flag)
+			}
+			cmd.url = flag
+			continue
+		}
+		if strings.HasPrefix(flag, "--") {
+			if name, v, ok := strings.Cut(flag, "="); ok {
+				flag, value, hasValue = name, v, true
+			}
+		} else if len(flag) > 2 {
+			if _, ok := curlFlagsWithValue[flag[:2]]; ok {
+				// -XPOST
+				flag, value, hasValue = flag[:2], flag[2:], true
+			} else {
+				// -sSL
+				for _, f := range flag[1:] {
+					if _, ok := curlFlagsIgnored["-"+string(f)]; !ok {
+						return nil, fmt.Errorf("unsupported curl option '-%c' in '%s'", f, flag)
+					}
+				}
+				continue
+			}
+		}
+		if long := curlFlagsWithValue[flag]; long != "" {
+			flag = long
+		} else if long := curlFlagsIgnored[flag]; long != "" {
+			flag = long
+		}
+		switch flag {
+		case "--get", "-G":
+			cmd.get = true
+			continue
+		case "--head", "-I":
+			explicitMethod = http.MethodHead
+			continue
+		}
+		if _, ok := curlFlagsIgnored[flag]; ok {
+			continue
+		}
+		if _, ok := curlFlagsWithValue[flag]; !ok {
+			return nil, fmt.Errorf("unsupported curl option '%s'", flag)
+		}
+		if !hasValue {
+			i++
+			if i >= len(args) {
+				return nil, fmt.Errorf("curl option '%s' requires a value", flag)
+			}
+			value = args[i]
+		}
+		switch flag {
+		case "--request":
+			explicitMethod = strings.ToUpper(value)
+		case "--url":
+			cmd.url = value
+		case "--header":
+			name, v, ok := strings.Cut(value, ":")
+			if !ok {
+				// as curl, "X-Empty;" is a header without a value
+				if name, ok = strings.CutSuffix(strings.TrimSpace(value), ";"); !ok {
+					return nil, fmt.Errorf("invalid header '%s'", value)
+				}
+				header(name, "")
+				continue
+			}
+			if strings.TrimSpace(v) == "" {
+				// as curl, "X-Remove:" removes the header rather than sending it empty
+				continue
+			}
+			header(name, v)
+		case "--user":
+			header("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(value)))
+		case "--cookie":
+			if !strings.Contains(value, "=") {
+				return nil, fmt.Errorf("cookie files are not supported, '%s'", value)
+			}
+			header("Cookie", value)
+		case "--user-agent":
+			header("User-Agent", value)
+		case "--referer":
+			header("Referer", value)
+		case "--data", "--data-ascii", "--data-binary":
+			if file, ok := strings.CutPrefix(value, "@"); ok {
+				buf, err := os.ReadFile(file)
+				if err != nil {
+					return nil, err
+				}
+				if flag != "--data-binary" {
+					// as curl, line breaks are removed from files
+					buf = []byte(strings.NewReplacer("\r", "", "\n", "").Replace(string(buf)))
+				}
+				value = string(buf)
+			}
+			cmd.data = append(cmd.data, value)
+		case "--data-raw":
+			cmd.data = append(cmd.data, value)
+		case "--data-urlencode":
+			encoded, err := curlURLEncode(value)
+			if err != nil {
+				return nil, err
+			}
+			cmd.data = append(cmd.data, encoded)
+		case "--form", "--form-string":
+			part, err := curlFormPart(value, flag == "--form-string")
+			if err != nil {
+				return nil, err
+			}
+			cmd.form = append(cmd.form, part)
+		}
+	}
+	if cmd.url == "" {
+		return nil, fmt.Errorf("no url")
+	}
+	return cmd, cmd.resolve(explicitMethod)
+}
+
+// resolve the method, url and body as curl would from the options
+func (cmd *curlCommand) resolve(explicitMethod string) error {
+	rawURL := cmd.url
+	if !strings.HasPrefix(rawURL, "/") && !strings.Contains(rawURL, "://") {
+		// curl example.com/path or 127.0.0.1:8080/path
+		rawURL = "http://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if cmd.get && len(cmd.data) > 0 {
+		if u.RawQuery != "" {
+			u.RawQuery += "&"
+		}
+		u.RawQuery += strings.Join(cmd.data, "&")
+		cmd.data = nil
+	}
+	// the target decides where the request is sent
+	cmd.url = u.RequestURI()
+
+	cmd.method = http.MethodGet
+	switch {
+	case len(cmd.form) > 0:
+		cmd.method = http.MethodPost
+		if len(cmd.data) > 0 {
+			return fmt.Errorf("-d and -F cannot be used together")
+		}
+	case len(cmd.data) > 0:
+		cmd.method = http.MethodPost
+		cmd.body = []byte(strings.Join(cmd.data, "&"))
+		if !cmd.hasHeader("Content-Type") {
+			cmd.headers = append(cmd.headers, [2]string{"Content-Type", "application/x-www-form-urlencoded"})
+		}
+	}
+	if explicitMethod != "" {
+		cmd.method = explicitMethod
+	}
+	return nil
+}
+
+func (cmd *curlCommand) hasHeader(name string) bool {
+	for _, h := range cmd.headers {
+		if h[0] == name {
+			return true
+		}
+	}
+	return false
+}
+
+// curlURLEncode as --data-urlencode, "content", "=content", "name=content", "@file" or "name@file"
+func curlURLEncode(value string) (string, error) {
+	name, content := "", value
+	if i := strings.IndexAny(value, "=@"); i >= 0 {
+		name, content = value[:i], value[i+1:]
+		if value[i] == '@' {
+			buf, err := os.ReadFile(content)
+			if err != nil {
+				return "", err
+			}
+			content = string(buf)
+		}
+	}
+	if name == "" {
+		return url.QueryEscape(content), nil
+	}
+	return name + "=" + url.QueryEscape(content), nil
+}
+
+// curlFormPart from "name=value", "name=@file;type=text/plain" or "name=<file", the file content is sent as the value
+func curlFormPart(value string, literal bool) (multipartPart, error) {
+	name, content, ok := strings.Cut(value, "=")
+	if !ok {
+		return multipartPart{}, fmt.Errorf("invalid form field '%s'", value)
+	}
+	if literal || !(strings.HasPrefix(content, "@") || strings.HasPrefix(content, "<")) {
+		return multipartPart{field: name, content: []byte(content)}, nil
+	}
+	file, params, _ := strings.Cut(content[1:], ";")
+	buf, err := os.ReadFile(file)
+	if err != nil {
+		return multipartPart{}, err
+	}
+	if content[0] == '<' {
+		return multipartPart{field: name, content: buf}, nil
+	}
+	part := multipartPart{field: name, filename: filepath.Base(file), contentType: contentTypeByExtension(file), content: buf}
+	for _, p := range strings.Split(params, ";") {
+		k, v, _ := strings.Cut(p, "=")
+		switch strings.TrimSpace(k) {
+		case "type":
+			part.contentType = v
+		case "filename":
+			part.filename = v
+		}
+	}
+	return part, nil
+}
+
+// splitShellWords as a POSIX shell would, handling quotes, escapes and line continuations
+func splitShellWords(s string) ([]string, error) {
+	var (
+		words   []string
+		word    strings.Builder
+		inWord  bool
+		quote   rune
+		escaped bool
+	)
+	for _, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+			if r == '\n' {
+				// line continuation
+				continue
+			}
+			if quote == '"' && !strings.ContainsRune(`"\$`+"`", r) {
+				word.WriteRune('\\')
+			}
+			word.WriteRune(r)
+			inWord = true
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+				continue
+			}
+			word.WriteRune(r)
+		case r == '\\':
+			escaped = true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+				continue
+			}
+			word.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if escaped {
+		return nil, fmt.Errorf("trailing backslash")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
@@ -0,0 +1,184 @@
+package httptestclient_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/NearlyUnique/httptestclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_from_curl(t *testing.T) {
+	testData := []struct {
+		name    string
+		command string
+		method  string
+		url     string
+		header  http.Header
+		body    string
+	}{
+		{
+			name:    "get",
+			command: `curl https://api.example.com/items?q=1`,
+			method:  "GET",
+			url:     "/items?q=1",
+			header:  http.Header{},
+		},
+		{
+			name: "json post over lines",
+			command: `curl -sS -X POST 'https://api.example.com/items' \
+  -H 'Content-Type: application/json' \
+  -H "Accept: */*" \
+  -H 'X-Many: 1' -H 'X-Many: 2' \
+  --data-raw '{"name":"it'\''s"}' --compressed`,
+			method: "POST",
+			url:    "/items",
+			header: http.Header{
+				"Accept":       {"*/*"},
+				"Content-Type": {"application/json"},
+				"X-Many":       {"1", "2"},
+			},
+			body: `{"name":"it's"}`,
+		},
+		{
+			name:    "form data defaults to post",
+			command: `curl example.com/login -d user=bob --data-urlencode 'pass=a&b c' -u bob:secret -b 'a=1; b=2' -A agent`,
+			method:  "POST",
+			url:     "/login",
+			header: http.Header{
+				"Authorization": {"Basic Ym9iOnNlY3JldA=="},
+				"Content-Type":  {"application/x-www-form-urlencoded"},
+				"Cookie":        {"a=1; b=2"},
+				"User-Agent":    {"agent"},
+			},
+			body: "user=bob&pass=a%26b+c",
+		},
+		{
+			name:    "data as query",
+			command: `curl -G http://localhost:8080/search?x=1 -d q=go --data-urlencode 'tag=a b'`,
+			method:  "GET",
+			url:     "/search?x=1&q=go&tag=a+b",
+			header:  http.Header{},
+		},
+		{
+			name:    "data from a file",
+			command: `curl -XPUT --url=http://h/items/1 -H 'Content-Type: application/json' -d @testdata/curl/item.json`,
+			method:  "PUT",
+			url:     "/items/1",
+			header:  http.Header{"Content-Type": {"application/json"}},
+			body:    `{"name":"from file"}`,
+		},
+		{
+			name:    "head",
+			command: `curl -I http://h/`,
+			method:  "HEAD",
+			url:     "/",
+			header:  http.Header{},
+		},
+		{
+			name:    "ip and port without a scheme",
+			command: `curl 127.0.0.1:8080/items?q=1`,
+			method:  "GET",
+			url:     "/items?q=1",
+			header:  http.Header{},
+		},
+		{
+			name:    "host and port without a scheme",
+			command: `curl localhost:8080/items`,
+			method:  "GET",
+			url:     "/items",
+			header:  http.Header{},
+		},
+		{
+			name:    "empty headers",
+			command: `curl http://h/ -H 'X-Empty;' -H 'X-Removed:' -H 'X-Blank: '`,
+			method:  "GET",
+			url:     "/",
+			header:  http.Header{"X-Empty": {""}},
+		},
+		{
+			name:    "header values are literal",
+			command: `curl http://h/ -H 'X-Template: ${name}' -H 'Accept: text/plain'`,
+			method:  "GET",
+			url:     "/",
+			header:  http.Header{"Accept": {"text/plain"}, "X-Template": {"${name}"}},
+		},
+	}
+	for _, td := range testData {
+		t.Run(td.name, func(t *testing.T) {
+			req := httptestclient.FromCurl(t, td.command).BuildRequest()
+
+			require.NotNil(t, req)
+			assert.Equal(t, td.method, req.Method)
+			assert.Equal(t, td.url, req.URL.String())
+			assert.Equal(t, td.header, req.Header)
+			var body []byte
+			if req.Body != nil {
+				body, _ = io.ReadAll(req.Body)
+			}
+			assert.Equal(t, td.body, string(body))
+		})
+	}
+}
+
+func Test_from_curl_multipart(t *testing.T) {
+	var actual struct {
+		fields map[string][]string
+		file   uploadedFile
+	}
+	target := httptestclient.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseMultipartForm(1<<20))
+		actual.fields = r.MultipartForm.Value
+		h := r.MultipartForm.File["doc"][0]
+		f, _ := h.Open()
+		buf, _ := io.ReadAll(f)
+		actual.file = uploadedFile{filename: h.Filename, contentType: h.Header.Get("Content-Type"), content: string(buf)}
+	}))
+
+	_ = httptestclient.FromCurl(t, `curl http://h/upload -F title=hi -F 'note=<testdata/curl/note.txt' -F 'doc=@testdata/curl/note.txt;type=text/markdown'`).
+		DoSimpleTarget(target)
+
+	assert.Equal(t, map[string][]string{"title": {"hi"}, "note": {"hello"}}, actual.fields)
+	assert.Equal(t, uploadedFile{filename: "note.txt", contentType: "text/markdown", content: "hello"}, actual.file)
+}
+
+func Test_from_curl_output_of_curl(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer s.Close()
+	original := httptestclient.New(t).Patch("/items/1").Header("X-Tag", "a'b").BodyString(`{"a":"b c"}`)
+	_ = original.DoSimple(s)
+
+	req := httptestclient.FromCurl(t, original.Curl()).BuildRequest()
+
+	require.NotNil(t, req)
+	assert.Equal(t, original.BuildRequest().Header, req.Header)
+	body, _ := io.ReadAll(req.Body)
+	assert.Equal(t, `{"a":"b c"}`, string(body))
+}
+
+func Test_from_curl_errors(t *testing.T) {
+	testData := []struct {
+		command  string
+		expected string
+	}{
+		{`curl -X`, "curl option '--request' requires a value"},
+		{`curl --proxy p http://h/`, "unsupported curl option '--proxy'"},
+		{`curl -sZ http://h/`, "unsupported curl option '-Z' in '-sZ'"},
+		{`curl 'http://h/`, "unterminated ' quote"},
+		{`curl -H nocolon http://h/`, "invalid header 'nocolon'"},
+		{`curl -b cookies.txt http://h/`, "cookie files are not supported, 'cookies.txt'"},
+		{`curl -s`, "no url"},
+	}
+	for _, td := range testData {
+		t.Run(td.command, func(t *testing.T) {
+			ht := &hookedT{}
+
+			_ = httptestclient.FromCurl(ht, td.command)
+
+			assert.Equal(t, []string{"Expected no error, got " + td.expected}, ht.errors)
+		})
+	}
+}
@@ -0,0 +1,297 @@
+package httptestclient
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"slices"
+	"time"
+	"unicode/utf8"
+)
+
+// har HTTP Archive 1.2, http://www.softwareishard.com/blog/har-12-spec/
+type har struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	Comment         string      `json:"comment,omitempty"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harCookie    `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harCookie    `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harCookie struct {
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Path     string `json:"path,omitempty"`
+	Domain   string `json:"domain,omitempty"`
+	Expires  string `json:"expires,omitempty"`
+	HTTPOnly bool   `json:"httpOnly,omitempty"`
+	Secure   bool   `json:"secure,omitempty"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// harPostData Encoding is not part of HAR 1.2, which only allows it on the response content. A body that is not
+// UTF-8 is base64 encoded with Encoding "base64" as for the response, httptestclient-gen decodes it, other tools may not
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// ExportHAR writes every request sent and response received by this Client to path as an HTTP Archive when
+// the test finishes, redirects are separate entries. Call before sending, response bodies are recorded as they are
+// read and binary bodies are base64 encoded. Requires a TestingT with Cleanup, e.g. *testing.T
+func (c *Client) ExportHAR(path string) *Client {
+	c.exportHAR = true
+	exportHAR(c.t, path, func() []*exchange { return c.transcript })
+	return c
+}
+
+// ExportHAR writes the traffic of every Client using the session to path when the test finishes, see Client.ExportHAR.
+// Only traffic sent after the call is exported
+func (s *Session) ExportHAR(t TestingT, path string) *Session {
+	s.mu.Lock()
+	s.exportHAR = true
+	s.mu.Unlock()
+	exportHAR(t, path, func() []*exchange {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return slices.Clone(s.exchanges)
+	})
+	return s
+}
+
+func exportHAR(t TestingT, path string, exchanges func() []*exchange) {
+	h, ok := t.(testingHooks)
+	if !ok {
+		t.Errorf("ExportHAR requires Cleanup, use *testing.T")
+		return
+	}
+	h.Helper()
+	h.Cleanup(func() {
+		if err := writeHAR(path, exchanges()); err != nil {
+			t.Errorf("har export to '%s' failed: %v", path, err)
+		}
+	})
+}
+
+func writeHAR(path string, exchanges []*exchange) error {
+	doc := har{Log: harLog{
+		Version: "1.2",
+		Creator: harCreator{Name: "httptestclient", Version: moduleVersion()},
+		Entries: []harEntry{},
+	}}
+	for _, e := range exchanges {
+		doc.Log.Entries = append(doc.Log.Entries, e.harEntries()...)
+	}
+	buf, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf, 0o644)
+}
+
+// modulePath of httptestclient, used to find the version in the build info
+const modulePath = "github.com/NearlyUnique/httptestclient"
+
+// moduleVersion of httptestclient from the build info, "(devel)" when it is not known
+func moduleVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "(devel)"
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath && dep.Version != "" {
+			return dep.Version
+		}
+	}
+	if info.Main.Path == modulePath && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "(devel)"
+}
+
+// harEntries one per redirect followed and one for the final response
+func (e *exchange) harEntries() []harEntry {
+	var entries []harEntry
+	started := e.started
+	for i, hop := range e.hops {
+		entry := harEntry{
+			StartedDateTime: started.Format(time.RFC3339Nano),
+			Time:            millis(hop.at.Sub(started)),
+			Request:         newHARRequest(hop.response.Request, nil),
+			Response:        newHARResponse(hop.response, nil),
+			Timings:         e.timings(i, started, hop.at),
+		}
+		if i == 0 {
+			entry.Request = newHARRequest(e.request, e.requestBody)
+		}
+		entries = append(entries, entry)
+		started = hop.at
+	}
+	final := harEntry{
+		StartedDateTime: started.Format(time.RFC3339Nano),
+		Time:            millis(e.started.Add(e.duration).Sub(started)),
+		Request:         newHARRequest(e.request, e.requestBody),
+		Timings:         e.timings(len(e.hops), started, e.started.Add(e.duration)),
+	}
+	if e.response != nil {
+		if len(e.hops) > 0 {
+			final.Request = newHARRequest(e.response.Request, nil)
+		}
+		final.Response = newHARResponse(e.response, e.body())
+	} else {
+		final.Response = harResponse{HeadersSize: -1, BodySize: -1, Cookies: []harCookie{}, Headers: []harNameValue{}}
+	}
+	if e.err != nil {
+		final.Comment = e.err.Error()
+	}
+	return append(entries, final)
+}
+
+// timings of round trip i, from started until the response ended. Without a trace the time is reported as waiting
+func (e *exchange) timings(i int, started, ended time.Time) harTimings {
+	rt, ok := e.roundTripAt(i)
+	if !ok {
+		return harTimings{Wait: millis(ended.Sub(started))}
+	}
+	return harTimings{
+		Send:    millis(rt.wrote.Sub(started)),
+		Wait:    millis(rt.firstByte.Sub(rt.wrote)),
+		Receive: millis(max(ended.Sub(rt.firstByte), 0)),
+	}
+}
+
+func newHARRequest(req *http.Request, body []byte) harRequest {
+	r := harRequest{
+		Method:      req.Method,
+		URL:         req.URL.Redacted(),
+		HTTPVersion: "HTTP/1.1",
+		Cookies:     []harCookie{},
+		Headers:     harHeaders(req.Header),
+		QueryString: []harNameValue{},
+		HeadersSize: -1,
+		BodySize:    len(body),
+	}
+	for _, c := range req.Cookies() {
+		r.Cookies = append(r.Cookies, harCookie{Name: c.Name, Value: c.Value})
+	}
+	query := req.URL.Query()
+	for _, k := range sortedKeys(query) {
+		for _, v := range query[k] {
+			r.QueryString = append(r.QueryString, harNameValue{Name: k, Value: v})
+		}
+	}
+	if body != nil {
+		r.PostData = &harPostData{MimeType: req.Header.Get("Content-Type"), Text: string(body)}
+		if !utf8.Valid(body) {
+			r.PostData.Text = base64.StdEncoding.EncodeToString(body)
+			r.PostData.Encoding = "base64"
+		}
+	}
+	return r
+}
+
+func newHARResponse(resp *http.Response, body []byte) harResponse {
+	r := harResponse{
+		Status:      resp.StatusCode,
+		StatusText:  http.StatusText(resp.StatusCode),
+		HTTPVersion: resp.Proto,
+		Cookies:     []harCookie{},
+		Headers:     harHeaders(resp.Header),
+		Content:     harContent{Size: len(body), MimeType: resp.Header.Get("Content-Type")},
+		RedirectURL: resp.Header.Get("Location"),
+		HeadersSize: -1,
+		BodySize:    len(body),
+	}
+	if r.HTTPVersion == "" {
+		r.HTTPVersion = "HTTP/1.1"
+	}
+	for _, c := range resp.Cookies() {
+		hc := harCookie{Name: c.Name, Value: c.Value, Path: c.Path, Domain: c.Domain, HTTPOnly: c.HttpOnly, Secure: c.Secure}
+		if !c.Expires.IsZero() {
+			hc.Expires = c.Expires.Format(time.RFC3339)
+		}
+		r.Cookies = append(r.Cookies, hc)
+	}
+	if utf8.Valid(body) {
+		r.Content.Text = string(body)
+	} else {
+		r.Content.Text = base64.StdEncoding.EncodeToString(body)
+		r.Content.Encoding = "base64"
+	}
+	return r
+}
+
+func harHeaders(h http.Header) []harNameValue {
+	headers := []harNameValue{}
+	for _, k := range sortedKeys(h) {
+		for _, v := range h[k] {
+			headers = append(headers, harNameValue{Name: k, Value: v})
+		}
+	}
+	return headers
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
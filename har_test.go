@@ -0,0 +1,144 @@
+package httptestclient_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NearlyUnique/httptestclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type harFile struct {
+	Log struct {
+		Version string
+		Creator struct{ Name, Version string }
+		Entries []struct {
+			StartedDateTime string
+			Time            float64
+			Request         struct {
+				Method      string
+				URL         string
+				Cookies     []struct{ Name, Value string }
+				QueryString []struct{ Name, Value string }
+				PostData    *struct{ MimeType, Text, Encoding string }
+			}
+			Response struct {
+				Status      int
+				RedirectURL string
+				Cookies     []struct{ Name, Value string }
+				Content     struct {
+					MimeType string
+					Text     string
+					Encoding string
+				}
+			}
+			Timings struct{ Send, Wait, Receive float64 }
+		}
+	}
+}
+
+func readHAR(t *testing.T, path string) harFile {
+	t.Helper()
+	buf, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var h harFile
+	require.NoError(t, json.Unmarshal(buf, &h))
+	return h
+}
+
+func harServer() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /old", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "visited", Value: "yes"})
+		http.Redirect(w, r, "/new?from=old", http.StatusSeeOther)
+	})
+	mux.HandleFunc("GET /new", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	})
+	mux.HandleFunc("GET /binary", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte{0xff, 0xfe})
+	})
+	mux.HandleFunc("PUT /slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+	})
+	return mux
+}
+
+func Test_har_export(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "client.har")
+
+	t.Run("client", func(t *testing.T) {
+		c := httptestclient.New(t).ExportHAR(path)
+		_ = c.Post("/old").BodyString(`{"a":1}`).DoSimpleTarget(httptestclient.Handler(harServer()))
+	})
+
+	h := readHAR(t, path)
+	assert.Equal(t, "1.2", h.Log.Version)
+	assert.Equal(t, "httptestclient", h.Log.Creator.Name)
+	assert.NotEmpty(t, h.Log.Creator.Version)
+	assert.NotEqual(t, "1", h.Log.Creator.Version)
+	require.Len(t, h.Log.Entries, 2)
+	redirect, final := h.Log.Entries[0], h.Log.Entries[1]
+	assert.Equal(t, "POST", redirect.Request.Method)
+	assert.Equal(t, "http://example.com/old", redirect.Request.URL)
+	assert.Equal(t, `{"a":1}`, redirect.Request.PostData.Text)
+	assert.Equal(t, http.StatusSeeOther, redirect.Response.Status)
+	assert.Equal(t, "/new?from=old", redirect.Response.RedirectURL)
+	assert.Equal(t, "visited", redirect.Response.Cookies[0].Name)
+	assert.Equal(t, "GET", final.Request.Method)
+	assert.Equal(t, "http://example.com/new?from=old", final.Request.URL)
+	assert.Equal(t, "from", final.Request.QueryString[0].Name)
+	assert.Equal(t, "visited", final.Request.Cookies[0].Name)
+	assert.Nil(t, final.Request.PostData)
+	assert.Equal(t, http.StatusOK, final.Response.Status)
+	assert.Equal(t, `{"ok":true}`, final.Response.Content.Text)
+	assert.Equal(t, "application/json", final.Response.Content.MimeType)
+}
+
+func Test_har_export_of_a_session(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.har")
+
+	t.Run("session", func(t *testing.T) {
+		session := httptestclient.NewSession(httptestclient.Handler(harServer()))
+		_ = httptestclient.New(t).Get("/new?before=export").DoSimpleTarget(session)
+		session.ExportHAR(t, path)
+		_ = httptestclient.New(t).Get("/new").DoSimpleTarget(session)
+		_ = httptestclient.New(t).Get("/binary").DoSimpleTarget(session)
+	})
+
+	h := readHAR(t, path)
+	require.Len(t, h.Log.Entries, 2)
+	assert.Equal(t, "http://example.com/new", h.Log.Entries[0].Request.URL)
+	assert.Equal(t, "//4=", h.Log.Entries[1].Response.Content.Text)
+	assert.Equal(t, "base64", h.Log.Entries[1].Response.Content.Encoding)
+}
+
+func Test_har_export_timings_and_binary_request_bodies(t *testing.T) {
+	s := httptest.NewServer(harServer())
+	defer s.Close()
+	path := filepath.Join(t.TempDir(), "timings.har")
+
+	t.Run("client", func(t *testing.T) {
+		_ = httptestclient.New(t).ExportHAR(path).
+			Put("/slow").
+			BodyBytes([]byte{0xff, 0x00, 0x01}).
+			Header("Content-Type", "application/octet-stream").
+			DoSimple(s)
+	})
+
+	h := readHAR(t, path)
+	require.Len(t, h.Log.Entries, 1)
+	entry := h.Log.Entries[0]
+	assert.Equal(t, "/wAB", entry.Request.PostData.Text)
+	assert.Equal(t, "base64", entry.Request.PostData.Encoding)
+	assert.GreaterOrEqual(t, entry.Timings.Wait, 5.0)
+	assert.Less(t, entry.Timings.Wait, entry.Time)
+	assert.InDelta(t, entry.Time, entry.Timings.Send+entry.Timings.Wait+entry.Timings.Receive, 0.01)
+}
@@ -0,0 +1,414 @@
+package httptestclient
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HTTPFile requests parsed from a .http file as used by the VS Code REST Client and JetBrains IDEs
+//
+//	@host = http://localhost:8080
+//
+//	### create
+//	# @name create
+//	POST {{host}}/items
+//	Content-Type: application/json
+//
+//	{"name": "widget"}
+//
+//	### read
+//	# @expect-status 200
+//	GET {{host}}/items/{{create.response.body.$.id}}
+type HTTPFile struct {
+	Vars     map[string]string
+	Requests []HTTPFileRequest
+
+	dir string
+}
+
+// HTTPFileRequest a single request, ExpectStatus is set from a `# @expect-status 404` comment or a response
+// handler that asserts response.status. Captures are json paths, set by `client.global.set("name", response.body.id)`
+type HTTPFileRequest struct {
+	Name         string
+	RefName      string
+	Method       string
+	URL          string
+	Header       http.Header
+	Body         string
+	BodyFile     string
+	ExpectStatus int
+	Captures     map[string]string
+	Line         int
+}
+
+var (
+	httpMethods     = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS", "TRACE", "CONNECT"}
+	rxHTTPFileVar   = regexp.MustCompile(`^@([A-Za-z_][A-Za-z0-9_.-]*)\s*=\s*(.*)$`)
+	rxHTTPFileMeta  = regexp.MustCompile(`^(?:#|//)\s*@([a-z-]+)\s*(.*)$`)
+	rxHTTPFileRef   = regexp.MustCompile(`\{\{\s*([^{}]+?)\s*}}`)
+	rxHandlerStatus = regexp.MustCompile(`response\.status\s*===?\s*(\d{3})`)
+	rxHandlerGlobal = regexp.MustCompile(`client\.global\.set\(\s*["']([A-Za-z_][A-Za-z0-9_]*)["']\s*,\s*response\.body((?:\.[A-Za-z_][A-Za-z0-9_]*|\[\d+])*)\s*\)`)
+)
+
+// RunHTTPFile runs each request in the .http file at path against target as a step of a Scenario, any status
+// other than 2xx fails the test unless an expected status is annotated. `t` use `*testing.T`
+func RunHTTPFile(t TestingT, target Target, path string) {
+	if h, ok := t.(testingHooks); ok {
+		h.Helper()
+	}
+	f, err := LoadHTTPFile(path)
+	if err != nil {
+		t.Errorf("%v", err)
+		t.FailNow()
+		return
+	}
+	f.Run(t, target)
+}
+
+// LoadHTTPFile parses the file at path, body files, `< ./body.json`, are relative to the file
+func LoadHTTPFile(path string) (*HTTPFile, error) {
+	r, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = r.Close() }()
+	f, err := ParseHTTPFile(r)
+	if err != nil {
+		return nil, fmt.Errorf("http file '%s': %w", path, err)
+	}
+	f.dir = filepath.Dir(path)
+	return f, nil
+}
+
+// ParseHTTPFile requests separated by ###, file variables `@name = value` can be used in any request as {{name}}
+func ParseHTTPFile(r io.Reader) (*HTTPFile, error) {
+	f := &HTTPFile{Vars: map[string]string{}}
+	var (
+		req     *HTTPFileRequest
+		name    string
+		inBody  bool
+		body    []string
+		handler *strings.Builder
+	)
+	finish := func() error {
+		if req != nil {
+			req.Body = strings.TrimRight(strings.Join(body, "\n"), "\n")
+			if strings.HasPrefix(req.Body, "< ") && !strings.Contains(req.Body, "\n") {
+				req.BodyFile, req.Body = strings.TrimSpace(req.Body[2:]), ""
+			}
+			f.Requests = append(f.Requests, *req)
+		}
+		if handler != nil {
+			return fmt.Errorf("response handler is not closed with %%}")
+		}
+		req, name, inBody, body = nil, "", false, nil
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		trimmed := strings.TrimSpace(text)
+		switch {
+		case handler != nil:
+			before, closed := strings.CutSuffix(trimmed, "%}")
+			handler.WriteString(before + "\n")
+			if closed {
+				req.applyHandler(handler.String())
+				handler = nil
+			}
+			continue
+		case strings.HasPrefix(trimmed, "###"):
+			if err := finish(); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			name = strings.TrimSpace(strings.TrimPrefix(trimmed, "###"))
+			continue
+		case req != nil && strings.HasPrefix(trimmed, ">"):
+			script := strings.TrimSpace(strings.TrimPrefix(trimmed, ">"))
+			if !strings.HasPrefix(script, "{%") {
+				// handler files are not supported
+				continue
+			}
+			script = strings.TrimPrefix(script, "{%")
+			if before, closed := strings.CutSuffix(script, "%}"); closed {
+				req.applyHandler(before)
+				continue
+			}
+			handler = &strings.Builder{}
+			handler.WriteString(script + "\n")
+			continue
+		case inBody:
+			body = append(body, text)
+			continue
+		case trimmed == "":
+			if req != nil && req.URL != "" {
+				inBody = true
+			}
+			continue
+		}
+		if m := rxHTTPFileMeta.FindStringSubmatch(trimmed); m != nil {
+			if req == nil {
+				req = &HTTPFileRequest{Name: name}
+			}
+			switch m[1] {
+			case "name":
+				req.RefName = strings.TrimSpace(m[2])
+			case "expect-status":
+				status, err := strconv.Atoi(strings.TrimSpace(m[2]))
+				if err != nil {
+					return nil, fmt.Errorf("line %d: invalid expected status '%s'", line, m[2])
+				}
+				req.ExpectStatus = status
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "//") {
+			continue
+		}
+		if m := rxHTTPFileVar.FindStringSubmatch(trimmed); m != nil && (req == nil || req.URL == "") {
+			f.Vars[m[1]] = strings.TrimSpace(m[2])
+			continue
+		}
+		if req == nil {
+			req = &HTTPFileRequest{Name: name}
+		}
+		switch {
+		case req.URL == "":
+			req.Line = line
+			req.Method, req.URL = parseRequestLine(trimmed)
+			req.Header = http.Header{}
+		case strings.HasPrefix(trimmed, "?") || strings.HasPrefix(trimmed, "&"):
+			// multi-line query
+			req.URL += trimmed
+		default:
+			k, v, ok := strings.Cut(trimmed, ":")
+			if !ok {
+				return nil, fmt.Errorf("line %d: invalid header '%s'", line, trimmed)
+			}
+			req.Header.Add(strings.TrimSpace(k), strings.TrimSpace(v))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := finish(); err != nil {
+		return nil, fmt.Errorf("line %d: %w", line, err)
+	}
+	for i, r := range f.Requests {
+		if r.URL == "" {
+			return nil, fmt.Errorf("request %d has no request line", i+1)
+		}
+	}
+	return f, nil
+}
+
+// parseRequestLine "POST /items HTTP/1.1" or just a URL for a GET
+func parseRequestLine(line string) (method, rawURL string) {
+	method = http.MethodGet
+	if m, rest, ok := strings.Cut(line, " "); ok {
+		for _, hm := range httpMethods {
+			if m == hm {
+				method, line = m, strings.TrimSpace(rest)
+				break
+			}
+		}
+	}
+	if i := strings.LastIndex(line, " HTTP/"); i > 0 {
+		line = line[:i]
+	}
+	return method, line
+}
+
+// applyHandler the parts of a JavaScript response handler that can be understood, anything else is ignored
+func (r *HTTPFileRequest) applyHandler(script string) {
+	if m := rxHandlerStatus.FindStringSubmatch(script); m != nil {
+		r.ExpectStatus, _ = strconv.Atoi(m[1])
+	}
+	for _, m := range rxHandlerGlobal.FindAllStringSubmatch(script, -1) {
+		if r.Captures == nil {
+			r.Captures = map[string]string{}
+		}
+		r.Captures[m[1]] = "$" + m[2]
+	}
+}
+
+// Run the requests in order as steps of a Scenario
+func (f *HTTPFile) Run(t TestingT, target Target) {
+	if h, ok := t.(testingHooks); ok {
+		h.Helper()
+	}
+	sc := NewScenario(t, target)
+	responses := map[string]SimpleResponse{}
+	for i, r := range f.Requests {
+		name := r.Name
+		if name == "" {
+			name = r.RefName
+		}
+		if name == "" {
+			name = fmt.Sprintf("%d %s %s", i+1, r.Method, r.URL)
+		}
+		var captures []Capture
+		for k, path := range r.Captures {
+			captures = append(captures, CaptureJSONPath(k, path))
+		}
+		resp := sc.Step(name, func(c *Client) *Client {
+			return f.build(c, r, sc, responses)
+		}, captures...)
+		if r.RefName != "" {
+			responses[r.RefName] = resp
+		}
+	}
+}
+
+// build the request, {{references}} are replaced before the Client sees the values so they are sent as written,
+// values in the URL path or query are escaped
+func (f *HTTPFile) build(c *Client, r HTTPFileRequest, sc *Scenario, responses map[string]SimpleResponse) *Client {
+	if h, ok := c.t.(testingHooks); ok {
+		h.Helper()
+	}
+	var missing []string
+	expand := func(s string) string {
+		return f.expand(s, sc, responses, &missing, 0)
+	}
+	rawURL := f.expandURL(r.URL, sc, responses, &missing)
+	if u, err := url.Parse(rawURL); err == nil && u.IsAbs() {
+		// the target decides where the request is sent
+		rawURL = u.RequestURI()
+		if u.Fragment != "" {
+			rawURL += "#" + u.EscapedFragment()
+		}
+	}
+	c.Method(r.Method).URL("$0", Raw(rawURL))
+	// escaped so the Client does not expand the values a second time
+	for k, values := range r.Header {
+		for i, v := range values {
+			if i == 0 {
+				c.Header(k, escapeVars(expand(v)))
+			} else {
+				c.header.Add(k, escapeVars(expand(v)))
+			}
+		}
+	}
+	body := r.Body
+	if r.BodyFile != "" {
+		buf, err := os.ReadFile(filepath.Join(f.dir, r.BodyFile))
+		if c.hasError(err) {
+			return c
+		}
+		body = string(buf)
+	}
+	if body != "" {
+		c.BodyBytes([]byte(expand(body)))
+	}
+	if len(missing) > 0 {
+		c.failNow("line %d has no value for %s", r.Line, strings.Join(missing, ", "))
+		return c
+	}
+	if r.ExpectStatus != 0 {
+		if r.ExpectStatus >= 300 && r.ExpectStatus < 400 {
+			c.NoFollowRedirects()
+		}
+		c.ExpectedStatusCode(r.ExpectStatus)
+	} else {
+		// the Client only fails on 4xx and 5xx by default
+		c.expect2xx = true
+	}
+	return c
+}
+
+// expand {{name}}, {{$processEnv NAME}}, {{$timestamp}} and {{request.response.body.$.path}} or
+// {{request.response.headers.Name}}, file variables may refer to other variables
+func (f *HTTPFile) expand(s string, sc *Scenario, responses map[string]SimpleResponse, missing *[]string, depth int) string {
+	return rxHTTPFileRef.ReplaceAllStringFunc(s, func(ref string) string {
+		key := rxHTTPFileRef.FindStringSubmatch(ref)[1]
+		if v, ok := f.lookup(key, sc, responses); ok {
+			if depth < 10 {
+				return f.expand(v, sc, responses, missing, depth+1)
+			}
+			return v
+		}
+		*missing = append(*missing, ref)
+		return ref
+	})
+}
+
+// expandURL as expand, values after the scheme and host are escaped for the path or query
+func (f *HTTPFile) expandURL(s string, sc *Scenario, responses map[string]SimpleResponse, missing *[]string) string {
+	var sb strings.Builder
+	last := 0
+	for _, m := range rxHTTPFileRef.FindAllStringIndex(s, -1) {
+		sb.WriteString(s[last:m[0]])
+		v := f.expand(s[m[0]:m[1]], sc, responses, missing, 0)
+		switch prefix := sb.String(); {
+		case strings.Contains(prefix, "?"):
+			v = url.QueryEscape(v)
+		case inPath(prefix):
+			v = url.PathEscape(v)
+		}
+		sb.WriteString(v)
+		last = m[1]
+	}
+	sb.WriteString(s[last:])
+	return sb.String()
+}
+
+// inPath true when a value following prefix is in the path of a URL, rather than the scheme and host
+func inPath(prefix string) bool {
+	if prefix == "" {
+		return false
+	}
+	if _, rest, ok := strings.Cut(prefix, "://"); ok {
+		return strings.Contains(rest, "/")
+	}
+	return true
+}
+
+func (f *HTTPFile) lookup(key string, sc *Scenario, responses map[string]SimpleResponse) (string, bool) {
+	if name, ok := strings.CutPrefix(key, "$processEnv "); ok {
+		return os.LookupEnv(strings.TrimSpace(name))
+	}
+	if key == "$timestamp" {
+		return strconv.FormatInt(time.Now().Unix(), 10), true
+	}
+	if v := sc.Value(key); v != nil {
+		return fmt.Sprintf("%v", v), true
+	}
+	if v, ok := f.Vars[key]; ok {
+		return v, true
+	}
+	ref, rest, ok := strings.Cut(key, ".response.")
+	if !ok {
+		return "", false
+	}
+	resp, ok := responses[ref]
+	if !ok || resp.Response == nil {
+		return "", false
+	}
+	if path, ok := strings.CutPrefix(rest, "body."); ok {
+		vars := map[string]any{}
+		if err := CaptureJSONPath("v", path)(resp, vars); err != nil {
+			return "", false
+		}
+		return fmt.Sprintf("%v", vars["v"]), true
+	}
+	if rest == "body" || rest == "body.*" {
+		return resp.Body, true
+	}
+	if name, ok := strings.CutPrefix(rest, "headers."); ok {
+		v := resp.Header.Get(name)
+		return v, v != ""
+	}
+	return "", false
+}
@@ -0,0 +1,132 @@
+package httptestclient_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/NearlyUnique/httptestclient"
+	"github.com/NearlyUnique/httptestclient/internal/self"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_run_http_file(t *testing.T) {
+	t.Setenv("HTC_TEST_SOURCE", "tests")
+
+	httptestclient.RunHTTPFile(t, httptestclient.Handler(itemServer()), "testdata/http/items.http")
+}
+
+func Test_http_file_values_are_sent_as_written(t *testing.T) {
+	var actual struct {
+		path  string
+		query string
+		token string
+	}
+	target := httptestclient.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actual.path = r.URL.EscapedPath()
+		actual.query = r.URL.RawQuery
+		actual.token = r.Header.Get("X-Token")
+	}))
+	f, err := httptestclient.ParseHTTPFile(strings.NewReader(`@host = http://example.com/api
+@name = a b/c?
+@token = ${secret}
+GET {{host}}/items/{{name}}?q={{name}}
+X-Token: {{token}}
+`))
+	require.NoError(t, err)
+
+	f.Run(t, target)
+
+	assert.Equal(t, "/api/items/a%20b%2Fc%3F", actual.path)
+	assert.Equal(t, "q=a+b%2Fc%3F", actual.query)
+	assert.Equal(t, "${secret}", actual.token)
+}
+
+func Test_http_file_status_must_be_2xx_unless_annotated(t *testing.T) {
+	target := httptestclient.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotModified)
+	}))
+
+	t.Run("without an annotation", func(t *testing.T) {
+		var messages []string
+		f, err := httptestclient.ParseHTTPFile(strings.NewReader("GET /items\n"))
+		require.NoError(t, err)
+
+		f.Run(self.NewFakeTester(func(format string, args ...interface{}) {
+			messages = append(messages, fmt.Sprintf(format, args...))
+		}), target)
+
+		assert.Equal(t, []string{"expected 2xx, got 304"}, messages)
+	})
+	t.Run("with an annotation", func(t *testing.T) {
+		f, err := httptestclient.ParseHTTPFile(strings.NewReader("# @expect-status 304\nGET /items\n"))
+		require.NoError(t, err)
+
+		f.Run(t, target)
+	})
+}
+
+func Test_parse_http_file(t *testing.T) {
+	f, err := httptestclient.ParseHTTPFile(strings.NewReader(`@host = http://example.com
+GET {{host}}/a
+
+### second
+# @name two
+# @expect-status 302
+POST /b HTTP/1.1
+Accept: text/plain
+Accept: text/html
+
+line 1
+line 2
+
+###
+/c
+> {% client.global.set("token", response.body.auth[0].token); %}
+`))
+
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"host": "http://example.com"}, f.Vars)
+	assert.Equal(t, []httptestclient.HTTPFileRequest{
+		{Method: "GET", URL: "{{host}}/a", Header: http.Header{}, Line: 2},
+		{
+			Name:         "second",
+			RefName:      "two",
+			Method:       "POST",
+			URL:          "/b",
+			Header:       http.Header{"Accept": {"text/plain", "text/html"}},
+			Body:         "line 1\nline 2",
+			ExpectStatus: 302,
+			Line:         7,
+		},
+		{
+			Method:   "GET",
+			URL:      "/c",
+			Header:   http.Header{},
+			Captures: map[string]string{"token": "$.auth[0].token"},
+			Line:     15,
+		},
+	}, f.Requests)
+}
+
+func Test_parse_http_file_errors(t *testing.T) {
+	testData := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{"bad header", "GET /a\nnot a header", "line 2: invalid header 'not a header'"},
+		{"bad status", "# @expect-status abc\nGET /a", "line 1: invalid expected status 'abc'"},
+		{"no request line", "# @name a\n###\nGET /b", "request 1 has no request line"},
+		{"open handler", "GET /a\n\n> {%\nclient.test()", "line 4: response handler is not closed with %}"},
+	}
+	for _, td := range testData {
+		t.Run(td.name, func(t *testing.T) {
+			_, err := httptestclient.ParseHTTPFile(strings.NewReader(td.content))
+
+			assert.EqualError(t, err, td.expected)
+		})
+	}
+}
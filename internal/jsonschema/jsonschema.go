@@ -0,0 +1,666 @@
+// Package jsonschema validates decoded json against a JSON Schema, also decoded json. Numbers may be float64 or
+// json.Number, decode with UseNumber to keep every digit of large integers. It covers the keywords used by
+// OpenAPI 3.0 schemas, including nullable and boolean exclusiveMinimum, and the common keywords of draft 2020-12.
+// Every violation is reported with the JSON pointer of the offending value
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// maxDepth of nested schemas, protects against $ref cycles that never consume the instance
+const maxDepth = 200
+
+// Violation of a schema by a value
+type Violation struct {
+	// Path JSON pointer to the value in the instance, "" is the whole instance
+	Path string
+	// Message describing the violation
+	Message string
+}
+
+func (v Violation) String() string {
+	if v.Path == "" {
+		return "(root): " + v.Message
+	}
+	return v.Path + ": " + v.Message
+}
+
+// Validator of instances against schemas within a root document, the root is used to resolve local $ref values
+type Validator struct {
+	root any
+
+	mu      sync.Mutex
+	regexps map[string]*regexp.Regexp
+}
+
+// New validator, root is a decoded json document, e.g. a schema or an OpenAPI document
+func New(root any) *Validator {
+	return &Validator{root: root, regexps: map[string]*regexp.Regexp{}}
+}
+
+// Direction of an OpenAPI message, readOnly properties are not required in a Request and writeOnly properties
+// are not required in a Response
+type Direction int
+
+const (
+	// Any message, every required property must be present
+	Any Direction = iota
+	// Request sent to a server
+	Request
+	// Response from a server
+	Response
+)
+
+// Validate instance against the whole root document as a schema
+func Validate(schema, instance any) []Violation {
+	return New(schema).Validate(schema, instance)
+}
+
+// Validate instance against schema, a node within the root document
+func (v *Validator) Validate(schema, instance any) []Violation {
+	return v.ValidateAs(Any, schema, instance)
+}
+
+// ValidateAs validates a message in the given direction
+func (v *Validator) ValidateAs(direction Direction, schema, instance any) []Violation {
+	r := &run{Validator: v, direction: direction}
+	r.validate(schema, instance, "", 0)
+	return r.out
+}
+
+// run of a single validation
+type run struct {
+	*Validator
+	direction Direction
+	out       []Violation
+}
+
+func (r *run) add(path, format string, args ...any) {
+	r.out = append(r.out, Violation{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// Resolve a local reference, a JSON pointer "#/components/schemas/Item" or an anchor "#item"
+func (v *Validator) Resolve(ref string) (any, error) {
+	pointer, ok := strings.CutPrefix(ref, "#")
+	if !ok {
+		return nil, fmt.Errorf("only local references are supported, '%s'", ref)
+	}
+	node := v.root
+	if pointer == "" {
+		return node, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		if anchor := findAnchor(v.root, pointer); anchor != nil {
+			return anchor, nil
+		}
+		return nil, fmt.Errorf("reference '%s' not found", ref)
+	}
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		token, _ = url.PathUnescape(token)
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		switch n := node.(type) {
+		case map[string]any:
+			next, ok := n[token]
+			if !ok {
+				return nil, fmt.Errorf("reference '%s' not found", ref)
+			}
+			node = next
+		case []any:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(n) {
+				return nil, fmt.Errorf("reference '%s' not found", ref)
+			}
+			node = n[i]
+		default:
+			return nil, fmt.Errorf("reference '%s' not found", ref)
+		}
+	}
+	return node, nil
+}
+
+// findAnchor the schema with "$anchor": name
+func findAnchor(node any, name string) any {
+	switch n := node.(type) {
+	case map[string]any:
+		if n["$anchor"] == name {
+			return n
+		}
+		for _, k := range sortedKeys(n) {
+			if found := findAnchor(n[k], name); found != nil {
+				return found
+			}
+		}
+	case []any:
+		for _, e := range n {
+			if found := findAnchor(e, name); found != nil {
+				return found
+			}
+		}
+	}
+	return nil
+}
+
+func (r *run) validate(schema, instance any, path string, depth int) {
+	if depth > maxDepth {
+		r.add(path, "schema is nested too deeply, is there a $ref cycle?")
+		return
+	}
+	switch s := schema.(type) {
+	case bool:
+		if !s {
+			r.add(path, "no value is allowed")
+		}
+	case map[string]any:
+		r.validateObject(s, instance, path, depth)
+	default:
+		r.add(path, "invalid schema %s", jsonText(schema))
+	}
+}
+
+func (r *run) validateObject(s map[string]any, instance any, path string, depth int) {
+	if instance == nil && s["nullable"] == true {
+		// OpenAPI 3.0, also allows null for a sibling $ref
+		return
+	}
+	if ref, ok := s["$ref"].(string); ok {
+		target, err := r.Resolve(ref)
+		if err != nil {
+			r.add(path, "%v", err)
+			return
+		}
+		r.validate(target, instance, path, depth+1)
+	}
+	if t, ok := s["type"]; ok && !matchesType(t, instance) {
+		r.add(path, "expected %s, got %s", typeNames(t), typeName(instance))
+		return
+	}
+	if enum, ok := s["enum"].([]any); ok && !containsJSON(enum, instance) {
+		r.add(path, "must be one of %s", jsonText(enum))
+	}
+	if c, ok := s["const"]; ok && !equalJSON(c, instance) {
+		r.add(path, "must be %s", jsonText(c))
+	}
+
+	switch x := instance.(type) {
+	case string:
+		r.validateString(s, x, path)
+	case float64, json.Number:
+		if n, ok := bigNumber(x); ok {
+			r.validateNumber(s, n, path)
+		}
+	case []any:
+		r.validateArray(s, x, path, depth)
+	case map[string]any:
+		r.validateProperties(s, x, path, depth)
+	}
+
+	if all, ok := s["allOf"].([]any); ok {
+		for _, sub := range all {
+			r.validate(sub, instance, path, depth+1)
+		}
+	}
+	if anyOf, ok := s["anyOf"].([]any); ok && r.countMatches(anyOf, instance, path, depth, 1) == 0 {
+		r.add(path, "does not match any schema in anyOf")
+	}
+	if oneOf, ok := s["oneOf"].([]any); ok {
+		if n := r.countMatches(oneOf, instance, path, depth, 2); n == 0 {
+			r.add(path, "does not match any schema in oneOf")
+		} else if n > 1 {
+			r.add(path, "matches more than one schema in oneOf")
+		}
+	}
+	if not, ok := s["not"]; ok && r.matches(not, instance, path, depth) {
+		r.add(path, "must not match the schema in not")
+	}
+	if condition, ok := s["if"]; ok {
+		if r.matches(condition, instance, path, depth) {
+			if then, ok := s["then"]; ok {
+				r.validate(then, instance, path, depth+1)
+			}
+		} else if otherwise, ok := s["else"]; ok {
+			r.validate(otherwise, instance, path, depth+1)
+		}
+	}
+}
+
+// countMatches up to limit
+func (r *run) countMatches(schemas []any, instance any, path string, depth, limit int) int {
+	n := 0
+	for _, sub := range schemas {
+		if r.matches(sub, instance, path, depth) {
+			n++
+			if n == limit {
+				break
+			}
+		}
+	}
+	return n
+}
+
+func (r *run) matches(schema, instance any, path string, depth int) bool {
+	sub := &run{Validator: r.Validator, direction: r.direction}
+	sub.validate(schema, instance, path, depth+1)
+	return len(sub.out) == 0
+}
+
+func (r *run) validateString(s map[string]any, x string, path string) {
+	length := utf8.RuneCountInString(x)
+	if n, ok := number(s["minLength"]); ok && float64(length) < n {
+		r.add(path, "length must be >= %s", formatNumber(n))
+	}
+	if n, ok := number(s["maxLength"]); ok && float64(length) > n {
+		r.add(path, "length must be <= %s", formatNumber(n))
+	}
+	if p, ok := s["pattern"].(string); ok {
+		rx, err := r.regexp(p)
+		if err != nil {
+			r.add(path, "invalid pattern '%s': %v", p, err)
+		} else if !rx.MatchString(x) {
+			r.add(path, "does not match pattern '%s'", p)
+		}
+	}
+	if f, ok := s["format"].(string); ok && !validFormat(f, x) {
+		r.add(path, "is not a valid %s", f)
+	}
+}
+
+func (r *run) validateNumber(s map[string]any, x *big.Float, path string) {
+	if n, ok := bigNumber(s["minimum"]); ok {
+		if s["exclusiveMinimum"] == true && x.Cmp(n) <= 0 {
+			r.add(path, "must be > %s", formatBig(n))
+		} else if x.Cmp(n) < 0 {
+			r.add(path, "must be >= %s", formatBig(n))
+		}
+	}
+	if n, ok := bigNumber(s["maximum"]); ok {
+		if s["exclusiveMaximum"] == true && x.Cmp(n) >= 0 {
+			r.add(path, "must be < %s", formatBig(n))
+		} else if x.Cmp(n) > 0 {
+			r.add(path, "must be <= %s", formatBig(n))
+		}
+	}
+	if n, ok := bigNumber(s["exclusiveMinimum"]); ok && x.Cmp(n) <= 0 {
+		r.add(path, "must be > %s", formatBig(n))
+	}
+	if n, ok := bigNumber(s["exclusiveMaximum"]); ok && x.Cmp(n) >= 0 {
+		r.add(path, "must be < %s", formatBig(n))
+	}
+	if n, ok := number(s["multipleOf"]); ok && n > 0 {
+		f, _ := x.Float64()
+		if q := f / n; math.Abs(q-math.Round(q)) > 1e-9 {
+			r.add(path, "must be a multiple of %s", formatNumber(n))
+		}
+	}
+	switch s["format"] {
+	case "int32":
+		if !inRange(x, math.MinInt32, math.MaxInt32) {
+			r.add(path, "is not a valid int32")
+		}
+	case "int64":
+		if !inRange(x, math.MinInt64, math.MaxInt64) {
+			r.add(path, "is not a valid int64")
+		}
+	}
+}
+
+// inRange x is an integer from minimum to maximum inclusive
+func inRange(x *big.Float, minimum, maximum int64) bool {
+	return x.IsInt() && x.Cmp(new(big.Float).SetInt64(minimum)) >= 0 && x.Cmp(new(big.Float).SetInt64(maximum)) <= 0
+}
+
+func (r *run) validateArray(s map[string]any, x []any, path string, depth int) {
+	if n, ok := number(s["minItems"]); ok && float64(len(x)) < n {
+		r.add(path, "must have at least %s items", formatNumber(n))
+	}
+	if n, ok := number(s["maxItems"]); ok && float64(len(x)) > n {
+		r.add(path, "must have at most %s items", formatNumber(n))
+	}
+	if s["uniqueItems"] == true {
+	unique:
+		for i := range x {
+			for j := i + 1; j < len(x); j++ {
+				if equalJSON(x[i], x[j]) {
+					r.add(path, "items %d and %d are equal, items must be unique", i, j)
+					break unique
+				}
+			}
+		}
+	}
+	if contains, ok := s["contains"]; ok {
+		n := 0
+		for i, item := range x {
+			if r.matches(contains, item, path+"/"+strconv.Itoa(i), depth) {
+				n++
+			}
+		}
+		minimum, ok := number(s["minContains"])
+		if !ok {
+			minimum = 1
+		}
+		if float64(n) < minimum {
+			r.add(path, "must contain at least %s matching items, found %d", formatNumber(minimum), n)
+		}
+		if maximum, ok := number(s["maxContains"]); ok && float64(n) > maximum {
+			r.add(path, "must contain at most %s matching items, found %d", formatNumber(maximum), n)
+		}
+	}
+	prefix, _ := s["prefixItems"].([]any)
+	for i, item := range x {
+		if i < len(prefix) {
+			r.validate(prefix[i], item, path+"/"+strconv.Itoa(i), depth+1)
+		} else if items, ok := s["items"]; ok {
+			r.validate(items, item, path+"/"+strconv.Itoa(i), depth+1)
+		}
+	}
+}
+
+func (r *run) validateProperties(s map[string]any, x map[string]any, path string, depth int) {
+	if n, ok := number(s["minProperties"]); ok && float64(len(x)) < n {
+		r.add(path, "must have at least %s properties", formatNumber(n))
+	}
+	if n, ok := number(s["maxProperties"]); ok && float64(len(x)) > n {
+		r.add(path, "must have at most %s properties", formatNumber(n))
+	}
+	properties, _ := s["properties"].(map[string]any)
+	if required, ok := s["required"].([]any); ok {
+		for _, name := range required {
+			name, _ := name.(string)
+			if _, ok := x[name]; !ok && !r.optional(properties[name]) {
+				r.add(path+"/"+escape(name), "is required")
+			}
+		}
+	}
+	if dependent, ok := s["dependentRequired"].(map[string]any); ok {
+		for _, name := range sortedKeys(dependent) {
+			if _, ok := x[name]; !ok {
+				continue
+			}
+			list, _ := dependent[name].([]any)
+			for _, d := range list {
+				d, _ := d.(string)
+				if _, ok := x[d]; !ok {
+					r.add(path+"/"+escape(d), "is required when '%s' is present", name)
+				}
+			}
+		}
+	}
+	patterns, _ := s["patternProperties"].(map[string]any)
+	additional, hasAdditional := s["additionalProperties"]
+	names, hasNames := s["propertyNames"]
+	for _, name := range sortedKeys(x) {
+		child := path + "/" + escape(name)
+		if hasNames {
+			r.validate(names, name, child, depth+1)
+		}
+		p, matched := properties[name]
+		if matched {
+			r.validate(p, x[name], child, depth+1)
+		}
+		for _, pattern := range sortedKeys(patterns) {
+			rx, err := r.regexp(pattern)
+			if err != nil {
+				r.add(path, "invalid pattern '%s': %v", pattern, err)
+				continue
+			}
+			if rx.MatchString(name) {
+				matched = true
+				r.validate(patterns[pattern], x[name], child, depth+1)
+			}
+		}
+		if matched {
+			continue
+		}
+		if additional == false {
+			r.add(child, "is not allowed")
+		} else if hasAdditional {
+			r.validate(additional, x[name], child, depth+1)
+		}
+	}
+}
+
+// optional properties in this direction even when required
+func (r *run) optional(property any) bool {
+	p, _ := property.(map[string]any)
+	if ref, ok := p["$ref"].(string); ok {
+		if target, err := r.Resolve(ref); err == nil {
+			p, _ = target.(map[string]any)
+		}
+	}
+	switch r.direction {
+	case Request:
+		return p["readOnly"] == true
+	case Response:
+		return p["writeOnly"] == true
+	}
+	return false
+}
+
+func (v *Validator) regexp(pattern string) (*regexp.Regexp, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if rx, ok := v.regexps[pattern]; ok {
+		return rx, nil
+	}
+	rx, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	v.regexps[pattern] = rx
+	return rx, nil
+}
+
+var (
+	rxUUID     = regexp.MustCompile(`^(?i)[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+	rxHostname = regexp.MustCompile(`^(?i)[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?(\.[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?)*$`)
+)
+
+// validFormat unknown formats are always valid
+func validFormat(format, s string) bool {
+	switch format {
+	case "date-time":
+		_, err := time.Parse(time.RFC3339Nano, s)
+		return err == nil
+	case "date":
+		_, err := time.Parse(time.DateOnly, s)
+		return err == nil
+	case "time":
+		_, err := time.Parse("15:04:05Z07:00", s)
+		return err == nil
+	case "email":
+		a, err := mail.ParseAddress(s)
+		return err == nil && a.Address == s
+	case "uuid":
+		return rxUUID.MatchString(s)
+	case "uri":
+		u, err := url.Parse(s)
+		return err == nil && u.IsAbs()
+	case "uri-reference":
+		_, err := url.Parse(s)
+		return err == nil
+	case "hostname":
+		return len(s) <= 253 && rxHostname.MatchString(s)
+	case "ipv4":
+		ip := net.ParseIP(s)
+		return ip != nil && ip.To4() != nil && !strings.Contains(s, ":")
+	case "ipv6":
+		ip := net.ParseIP(s)
+		return ip != nil && strings.Contains(s, ":")
+	case "regex":
+		_, err := regexp.Compile(s)
+		return err == nil
+	}
+	return true
+}
+
+func matchesType(t, instance any) bool {
+	switch x := t.(type) {
+	case string:
+		return isType(x, instance)
+	case []any:
+		for _, name := range x {
+			if s, ok := name.(string); ok && isType(s, instance) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isType(name string, instance any) bool {
+	switch name {
+	case "integer":
+		n, ok := bigNumber(instance)
+		return ok && n.IsInt()
+	case "number":
+		_, ok := bigNumber(instance)
+		return ok
+	}
+	return typeName(instance) == name
+}
+
+func typeNames(t any) string {
+	if names, ok := t.([]any); ok {
+		parts := make([]string, len(names))
+		for i, n := range names {
+			parts[i] = fmt.Sprint(n)
+		}
+		return strings.Join(parts, " or ")
+	}
+	return fmt.Sprint(t)
+}
+
+// typeName of a decoded json value
+func typeName(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64, json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func number(v any) (float64, bool) {
+	n, ok := bigNumber(v)
+	if !ok {
+		return 0, false
+	}
+	f, _ := n.Float64()
+	return f, true
+}
+
+// numberPrecision in bits, every digit of an id or amount is kept, a float64 converts exactly
+const numberPrecision = 512
+
+// bigNumber of a decoded json number, a float64 or, decoded with UseNumber, a json.Number
+func bigNumber(v any) (*big.Float, bool) {
+	switch n := v.(type) {
+	case float64:
+		if math.IsInf(n, 0) || math.IsNaN(n) {
+			return nil, false
+		}
+		return new(big.Float).SetPrec(numberPrecision).SetFloat64(n), true
+	case int:
+		return new(big.Float).SetPrec(numberPrecision).SetInt64(int64(n)), true
+	case json.Number:
+		f, _, err := big.ParseFloat(n.String(), 10, numberPrecision, big.ToNearestEven)
+		return f, err == nil
+	}
+	return nil, false
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func formatBig(n *big.Float) string {
+	f, _ := n.Float64()
+	return formatNumber(f)
+}
+
+func containsJSON(values []any, v any) bool {
+	for _, e := range values {
+		if equalJSON(e, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// equalJSON compares decoded json, numbers by value at any depth
+func equalJSON(a, b any) bool {
+	if na, ok := bigNumber(a); ok {
+		nb, ok := bigNumber(b)
+		return ok && na.Cmp(nb) == 0
+	}
+	switch x := a.(type) {
+	case []any:
+		y, ok := b.([]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equalJSON(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]any:
+		y, ok := b.(map[string]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, v := range x {
+			if w, ok := y[k]; !ok || !equalJSON(v, w) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func jsonText(v any) string {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(buf)
+}
+
+// escape a JSON pointer token
+func escape(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
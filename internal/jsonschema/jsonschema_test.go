@@ -0,0 +1,114 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decode(t *testing.T, s string) any {
+	t.Helper()
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	var v any
+	require.NoError(t, dec.Decode(&v))
+	return v
+}
+
+func messages(violations []Violation) []string {
+	var result []string
+	for _, v := range violations {
+		result = append(result, v.String())
+	}
+	return result
+}
+
+func Test_keywords(t *testing.T) {
+	testCases := []struct {
+		name     string
+		schema   string
+		instance string
+		expected []string
+	}{
+		{"type", `{"type":"string"}`, `1`, []string{"(root): expected string, got number"}},
+		{"type list", `{"type":["string","null"]}`, `null`, nil},
+		{"integer", `{"type":"integer"}`, `1.5`, []string{"(root): expected integer, got number"}},
+		{"nullable", `{"type":"string","nullable":true}`, `null`, nil},
+		{"nullable ref", `{"$defs":{"id":{"type":"integer"}},"$ref":"#/$defs/id","nullable":true}`, `null`, nil},
+		{"int32", `{"format":"int32"}`, `2147483648`, []string{"(root): is not a valid int32"}},
+		{"int64", `{"format":"int64"}`, `-9223372036854775807`, nil},
+		{"int64 range", `{"format":"int64"}`, `1e19`, []string{"(root): is not a valid int64"}},
+		{"int64 max", `{"type":"integer","format":"int64"}`, `9223372036854775807`, nil},
+		{"int64 above max", `{"type":"integer","format":"int64"}`, `9223372036854775808`, []string{"(root): is not a valid int64"}},
+		{"int64 below min", `{"type":"integer","format":"int64"}`, `-9223372036854775809`, []string{"(root): is not a valid int64"}},
+		{"large integers are exact", `{"const":9007199254740993}`, `9007199254740992`, []string{"(root): must be 9007199254740993"}},
+		{"integer as decimal", `{"type":"integer"}`, `2.0`, nil},
+		{"enum", `{"enum":["a","b"]}`, `"c"`, []string{`(root): must be one of ["a","b"]`}},
+		{"const", `{"const":2}`, `2.0`, nil},
+		{"string lengths", `{"minLength":2,"maxLength":3}`, `"é"`, []string{"(root): length must be >= 2"}},
+		{"pattern", `{"pattern":"^[a-z]+$"}`, `"A1"`, []string{"(root): does not match pattern '^[a-z]+$'"}},
+		{"format", `{"format":"date-time"}`, `"yesterday"`, []string{"(root): is not a valid date-time"}},
+		{"unknown format", `{"format":"colour"}`, `"red"`, nil},
+		{"minimum", `{"minimum":1,"maximum":3}`, `4`, []string{"(root): must be <= 3"}},
+		{"exclusive 3.0", `{"minimum":1,"exclusiveMinimum":true}`, `1`, []string{"(root): must be > 1"}},
+		{"exclusive 2020-12", `{"exclusiveMaximum":1}`, `1`, []string{"(root): must be < 1"}},
+		{"multipleOf", `{"multipleOf":0.1}`, `0.3`, nil},
+		{"items", `{"items":{"type":"integer"},"maxItems":2}`, `[1,"a",3]`,
+			[]string{"(root): must have at most 2 items", "/1: expected integer, got string"}},
+		{"unique", `{"uniqueItems":true}`, `[1,{"a":1},{"a":1}]`, []string{"(root): items 1 and 2 are equal, items must be unique"}},
+		{"properties", `{"required":["a","b/c"],"properties":{"a":{"type":"object","properties":{"x~":{"type":"string"}}}},"additionalProperties":false}`,
+			`{"a":{"x~":1},"z":0}`, []string{"/b~1c: is required", "/a/x~0: expected string, got number", "/z: is not allowed"}},
+		{"additional schema", `{"additionalProperties":{"type":"number"}}`, `{"a":"1"}`, []string{"/a: expected number, got string"}},
+		{"allOf", `{"allOf":[{"minimum":2},{"maximum":1}]}`, `3`, []string{"(root): must be <= 1"}},
+		{"anyOf", `{"anyOf":[{"type":"string"},{"type":"boolean"}]}`, `1`, []string{"(root): does not match any schema in anyOf"}},
+		{"oneOf", `{"oneOf":[{"type":"number"},{"type":"integer"}]}`, `1`, []string{"(root): matches more than one schema in oneOf"}},
+		{"not", `{"not":{"type":"null"}}`, `null`, []string{"(root): must not match the schema in not"}},
+		{"false", `{"properties":{"a":false}}`, `{"a":1}`, []string{"/a: no value is allowed"}},
+		{"ref", `{"$defs":{"id":{"type":"integer"}},"items":{"$ref":"#/$defs/id"}}`, `[1,"2"]`, []string{"/1: expected integer, got string"}},
+		{"missing ref", `{"$ref":"#/$defs/nope"}`, `1`, []string{"(root): reference '#/$defs/nope' not found"}},
+		{"ref cycle", `{"$ref":"#"}`, `1`, []string{"(root): schema is nested too deeply, is there a $ref cycle?"}},
+		{"anchor", `{"$defs":{"n":{"$anchor":"name","type":"string"}},"properties":{"a":{"$ref":"#name"}}}`, `{"a":1}`,
+			[]string{"/a: expected string, got number"}},
+		{"prefixItems", `{"prefixItems":[{"type":"string"}],"items":false}`, `["a",1]`, []string{"/1: no value is allowed"}},
+		{"contains", `{"contains":{"type":"string"},"maxContains":1}`, `["a","b",1]`, []string{"(root): must contain at most 1 matching items, found 2"}},
+		{"no contains", `{"contains":{"type":"string"}}`, `[1]`, []string{"(root): must contain at least 1 matching items, found 0"}},
+		{"patternProperties", `{"patternProperties":{"^x-":{"type":"string"}},"additionalProperties":false}`, `{"x-a":1,"b":2}`,
+			[]string{"/b: is not allowed", "/x-a: expected string, got number"}},
+		{"propertyNames", `{"propertyNames":{"maxLength":2}}`, `{"abc":1}`, []string{"/abc: length must be <= 2"}},
+		{"dependentRequired", `{"dependentRequired":{"card":["cvv"]}}`, `{"card":"1"}`, []string{"/cvv: is required when 'card' is present"}},
+		{"if then", `{"if":{"properties":{"kind":{"const":"a"}}},"then":{"required":["a"]},"else":{"required":["b"]}}`, `{"kind":"a"}`,
+			[]string{"/a: is required"}},
+		{"if else", `{"if":{"properties":{"kind":{"const":"a"}}},"then":{"required":["a"]},"else":{"required":["b"]}}`, `{"kind":"z"}`,
+			[]string{"/b: is required"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, messages(Validate(decode(t, tc.schema), decode(t, tc.instance))))
+		})
+	}
+}
+
+func Test_float64_numbers(t *testing.T) {
+	var instance any
+	require.NoError(t, json.Unmarshal([]byte(`[1, 9223372036854775808, 1.5]`), &instance))
+
+	violations := Validate(decode(t, `{"items":{"type":"integer","format":"int64"}}`), instance)
+
+	assert.Equal(t, []string{"/1: is not a valid int64", "/2: expected integer, got number"}, messages(violations))
+}
+
+func Test_direction_of_read_and_write_only_properties(t *testing.T) {
+	root := decode(t, `{
+		"item": {"required":["id","secret"],"properties":{"id":{"readOnly":true},"secret":{"$ref":"#/secret"}}},
+		"secret": {"type":"string","writeOnly":true}
+	}`)
+	v := New(root)
+	schema := root.(map[string]any)["item"]
+
+	assert.Empty(t, v.ValidateAs(Request, schema, decode(t, `{"secret":"s"}`)))
+	assert.Empty(t, v.ValidateAs(Response, schema, decode(t, `{"id":1}`)))
+	assert.Equal(t, []string{"/secret: is required"}, messages(v.ValidateAs(Any, schema, decode(t, `{"id":1}`))))
+}
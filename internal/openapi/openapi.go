@@ -0,0 +1,534 @@
+// Package openapi loads OpenAPI 3 documents and validates http requests and responses against their operations
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/NearlyUnique/httptestclient/internal/jsonschema"
+	"gopkg.in/yaml.v3"
+)
+
+var methods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// Document an OpenAPI 3 document
+type Document struct {
+	root       map[string]any
+	validator  *jsonschema.Validator
+	basePaths  []string
+	operations []*Operation
+}
+
+// Operation of a path, Path is the template from the document, e.g. "/items/{id}"
+type Operation struct {
+	Method string
+	Path   string
+	ID     string
+
+	node       map[string]any
+	parameters []map[string]any
+	rx         *regexp.Regexp
+	names      []string
+	literals   int
+}
+
+func (op *Operation) String() string {
+	return op.Method + " " + op.Path
+}
+
+// Violation of the document by a request or response, In is where, e.g. "request body" or "query parameter 'limit'"
+// and Path the JSON pointer within a body
+type Violation struct {
+	In      string
+	Path    string
+	Message string
+}
+
+func (v Violation) String() string {
+	if v.Path == "" {
+		return v.In + ": " + v.Message
+	}
+	return v.In + " " + v.Path + ": " + v.Message
+}
+
+// Load a JSON or YAML document
+func Load(file string) (*Document, error) {
+	buf, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := Parse(buf)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return doc, nil
+}
+
+// Parse a JSON or YAML document
+func Parse(data []byte) (*Document, error) {
+	var raw any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	// round trip through json so that numbers and keys are as encoding/json would decode them
+	buf, err := json.Marshal(normalize(raw))
+	if err != nil {
+		return nil, err
+	}
+	decoded, err := decodeJSON(buf)
+	if err != nil {
+		return nil, err
+	}
+	root, ok := decoded.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("document must be an object")
+	}
+	version, _ := root["openapi"].(string)
+	if !strings.HasPrefix(version, "3.") {
+		return nil, fmt.Errorf("unsupported openapi version '%s', expected 3.x", version)
+	}
+	d := &Document{root: root, validator: jsonschema.New(root)}
+	d.basePaths = serverPaths(root["servers"])
+	paths, _ := root["paths"].(map[string]any)
+	for path, item := range paths {
+		item, ok := d.Resolve(item).(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("path '%s' is not an object", path)
+		}
+		shared := d.parameters(item["parameters"])
+		for _, method := range methods {
+			node, ok := item[method].(map[string]any)
+			if !ok {
+				continue
+			}
+			op := &Operation{Method: strings.ToUpper(method), Path: path, node: node}
+			op.ID, _ = node["operationId"].(string)
+			op.parameters = mergeParameters(shared, d.parameters(node["parameters"]))
+			op.rx, op.names, op.literals = compilePath(path)
+			d.operations = append(d.operations, op)
+		}
+	}
+	// literal paths are matched before templated ones, "/items/mine" before "/items/{id}"
+	sort.SliceStable(d.operations, func(i, j int) bool {
+		a, b := d.operations[i], d.operations[j]
+		if a.literals != b.literals {
+			return a.literals > b.literals
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Method < b.Method
+	})
+	return d, nil
+}
+
+// Operations in the document, ordered by path then method
+func (d *Document) Operations() []*Operation {
+	ops := append([]*Operation(nil), d.operations...)
+	sort.Slice(ops, func(i, j int) bool {
+		if ops[i].Path != ops[j].Path {
+			return ops[i].Path < ops[j].Path
+		}
+		return ops[i].Method < ops[j].Method
+	})
+	return ops
+}
+
+// Find the operation for method and the escaped path, with any server base path, the path parameters are returned
+// unescaped. When the path exists for other methods, allowed lists them
+func (d *Document) Find(method, path string) (op *Operation, params map[string]string, allowed []string) {
+	candidates := []string{path}
+	for _, base := range d.basePaths {
+		if rest, ok := strings.CutPrefix(path, base); ok && (rest == "" || rest[0] == '/') {
+			candidates = append(candidates, "/"+strings.TrimPrefix(rest, "/"))
+		}
+	}
+	for _, candidate := range candidates {
+		for _, op := range d.operations {
+			m := op.rx.FindStringSubmatch(candidate)
+			if m == nil {
+				continue
+			}
+			if op.Method != method {
+				if !slices.Contains(allowed, op.Method) {
+					allowed = append(allowed, op.Method)
+				}
+				continue
+			}
+			params = map[string]string{}
+			for i, name := range op.names {
+				params[name], _ = url.PathUnescape(m[i+1])
+			}
+			return op, params, nil
+		}
+	}
+	return nil, nil, allowed
+}
+
+// ValidateRequest against op, params are the path parameters from Find and body the request body that was sent
+func (d *Document) ValidateRequest(op *Operation, params map[string]string, req *http.Request, body []byte) []Violation {
+	var out []Violation
+	query := req.URL.Query()
+	for _, p := range op.parameters {
+		name, _ := p["name"].(string)
+		in, _ := p["in"].(string)
+		where := fmt.Sprintf("%s parameter '%s'", in, name)
+		var values []string
+		switch in {
+		case "path":
+			if v, ok := params[name]; ok {
+				values = []string{v}
+			}
+		case "query":
+			values = query[name]
+		case "header":
+			switch http.CanonicalHeaderKey(name) {
+			case "Accept", "Content-Type", "Authorization":
+				// described elsewhere in the document, ignored as parameters
+				continue
+			}
+			values = req.Header.Values(name)
+		default:
+			continue
+		}
+		out = append(out, d.validateParameter(p, where, values, jsonschema.Request)...)
+	}
+
+	requestBody, _ := d.Resolve(op.node["requestBody"]).(map[string]any)
+	if requestBody == nil {
+		return out
+	}
+	if len(body) == 0 {
+		if requestBody["required"] == true {
+			out = append(out, Violation{In: "request body", Message: "is required"})
+		}
+		return out
+	}
+	return append(out, d.validateContent("request body", requestBody["content"], req.Header.Get("Content-Type"), body, jsonschema.Request)...)
+}
+
+// Root of the document as decoded json
+func (d *Document) Root() map[string]any {
+	return d.root
+}
+
+// Node of the operation object as decoded json
+func (op *Operation) Node() map[string]any {
+	return op.node
+}
+
+// Parameters of the operation, including those shared by the path, with any $ref resolved
+func (op *Operation) Parameters() []map[string]any {
+	return op.parameters
+}
+
+// Responses documented for the operation, e.g. "200", "4XX" or "default"
+func (op *Operation) Responses() []string {
+	responses, _ := op.node["responses"].(map[string]any)
+	return sortedKeys(responses)
+}
+
+// ResponseKey of the documented response for status, an exact match, a range such as "4XX", or "default"
+func (op *Operation) ResponseKey(status int) (string, bool) {
+	responses, _ := op.node["responses"].(map[string]any)
+	code := strconv.Itoa(status)
+	for _, key := range []string{code, code[:1] + "XX", code[:1] + "xx", "default"} {
+		if _, ok := responses[key]; ok {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// ValidateResponse against op
+func (d *Document) ValidateResponse(op *Operation, status int, header http.Header, body []byte) []Violation {
+	key, ok := op.ResponseKey(status)
+	if !ok {
+		return []Violation{{In: "response status", Message: fmt.Sprintf("%d is not documented", status)}}
+	}
+	responses, _ := op.node["responses"].(map[string]any)
+	r, _ := d.Resolve(responses[key]).(map[string]any)
+	var out []Violation
+	headers, _ := r["headers"].(map[string]any)
+	for _, name := range sortedKeys(headers) {
+		if http.CanonicalHeaderKey(name) == "Content-Type" {
+			continue
+		}
+		h, _ := d.Resolve(headers[name]).(map[string]any)
+		out = append(out, d.validateParameter(h, fmt.Sprintf("response header '%s'", name), header.Values(name), jsonschema.Response)...)
+	}
+	content, _ := r["content"].(map[string]any)
+	if len(content) == 0 || op.Method == http.MethodHead || status == http.StatusNoContent || status == http.StatusNotModified {
+		return out
+	}
+	if len(body) == 0 {
+		return append(out, Violation{In: "response body", Message: "is empty"})
+	}
+	return append(out, d.validateContent("response body", content, header.Get("Content-Type"), body, jsonschema.Response)...)
+}
+
+// validateParameter values of a parameter or header, values are converted to the type of the schema
+func (d *Document) validateParameter(p map[string]any, where string, values []string, direction jsonschema.Direction) []Violation {
+	if len(values) == 0 {
+		if p["required"] == true {
+			return []Violation{{In: where, Message: "is required"}}
+		}
+		return nil
+	}
+	schema := p["schema"]
+	if schema == nil {
+		return nil
+	}
+	instance, err := d.parameterValue(schema, p, values)
+	if err != nil {
+		return []Violation{{In: where, Message: err.Error()}}
+	}
+	return d.schemaViolations(where, schema, instance, direction)
+}
+
+// parameterValue as the decoded json the schema expects, arrays are exploded or comma separated
+func (d *Document) parameterValue(schema any, p map[string]any, values []string) (any, error) {
+	s, _ := d.Resolve(schema).(map[string]any)
+	if !slices.Contains(schemaTypes(s), "array") {
+		return scalarValue(s, values[0])
+	}
+	// form style, the default for query parameters, explodes arrays to repeated values
+	explode := p["in"] == "query"
+	if e, ok := p["explode"].(bool); ok {
+		explode = e
+	}
+	if !explode {
+		values = strings.Split(strings.Join(values, ","), ",")
+	}
+	items := make([]any, len(values))
+	for i, v := range values {
+		item, err := scalarValue(d.Resolve(s["items"]), v)
+		if err != nil {
+			return nil, fmt.Errorf("item %d %w", i, err)
+		}
+		items[i] = item
+	}
+	return items, nil
+}
+
+// rxNumber a json number
+var rxNumber = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// scalarValue converted to the first of the schemas types it is valid for, numbers are json.Number so that every
+// digit is kept, a string is used when no type matches and string is one of them
+func scalarValue(schema any, value string) (any, error) {
+	s, _ := schema.(map[string]any)
+	types := schemaTypes(s)
+	converted := false
+	for _, t := range types {
+		switch t {
+		case "integer", "number":
+			converted = true
+			if rxNumber.MatchString(value) {
+				return json.Number(value), nil
+			}
+		case "boolean":
+			converted = true
+			if b, err := strconv.ParseBool(value); err == nil {
+				return b, nil
+			}
+		}
+	}
+	if converted && !slices.Contains(types, "string") {
+		return nil, fmt.Errorf("expected %s, got '%s'", strings.Join(types, " or "), value)
+	}
+	return value, nil
+}
+
+// schemaTypes of a schema, a single type or, from OpenAPI 3.1, a list
+func schemaTypes(s map[string]any) []string {
+	switch t := s["type"].(type) {
+	case string:
+		return []string{t}
+	case []any:
+		var types []string
+		for _, name := range t {
+			if name, ok := name.(string); ok {
+				types = append(types, name)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+// decodeJSON with numbers as json.Number
+func decodeJSON(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("invalid character after top-level value")
+	}
+	return v, nil
+}
+
+// validateContent body by its media type, only json bodies are validated against their schema
+func (d *Document) validateContent(where string, content any, contentType string, body []byte, direction jsonschema.Direction) []Violation {
+	media, _ := content.(map[string]any)
+	if len(media) == 0 {
+		return nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	entry, ok := media[mediaType]
+	if !ok {
+		entry, ok = media[strings.Split(mediaType, "/")[0]+"/*"]
+	}
+	if !ok {
+		entry, ok = media["*/*"]
+	}
+	if !ok {
+		return []Violation{{In: where, Message: fmt.Sprintf("content type '%s' is not documented, expected %s", contentType, strings.Join(sortedKeys(media), ", "))}}
+	}
+	m, _ := entry.(map[string]any)
+	schema, ok := m["schema"]
+	if !ok || !(strings.HasSuffix(mediaType, "/json") || strings.HasSuffix(mediaType, "+json")) {
+		return nil
+	}
+	instance, err := decodeJSON(body)
+	if err != nil {
+		return []Violation{{In: where, Message: fmt.Sprintf("invalid json: %v", err)}}
+	}
+	return d.schemaViolations(where, schema, instance, direction)
+}
+
+func (d *Document) schemaViolations(where string, schema, instance any, direction jsonschema.Direction) []Violation {
+	var out []Violation
+	for _, v := range d.validator.ValidateAs(direction, schema, instance) {
+		out = append(out, Violation{In: where, Path: v.Path, Message: v.Message})
+	}
+	return out
+}
+
+// parameters with any $ref resolved
+func (d *Document) parameters(list any) []map[string]any {
+	items, _ := list.([]any)
+	var result []map[string]any
+	for _, item := range items {
+		if p, ok := d.Resolve(item).(map[string]any); ok {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// Resolve a $ref object, other values are returned unchanged
+func (d *Document) Resolve(node any) any {
+	for range 32 {
+		m, ok := node.(map[string]any)
+		if !ok {
+			return node
+		}
+		ref, ok := m["$ref"].(string)
+		if !ok {
+			return node
+		}
+		target, err := d.validator.Resolve(ref)
+		if err != nil {
+			return nil
+		}
+		node = target
+	}
+	return nil
+}
+
+// mergeParameters operation parameters override path parameters with the same name and location
+func mergeParameters(shared, own []map[string]any) []map[string]any {
+	key := func(p map[string]any) string { return fmt.Sprint(p["in"], ":", p["name"]) }
+	result := append([]map[string]any(nil), own...)
+	seen := map[string]bool{}
+	for _, p := range own {
+		seen[key(p)] = true
+	}
+	for _, p := range shared {
+		if !seen[key(p)] {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+var rxTemplate = regexp.MustCompile(`\{([^}/]+)\}`)
+
+// compilePath template to a regexp matching an escaped path
+func compilePath(path string) (*regexp.Regexp, []string, int) {
+	var names []string
+	pattern := "^"
+	last := 0
+	for _, m := range rxTemplate.FindAllStringSubmatchIndex(path, -1) {
+		pattern += regexp.QuoteMeta(path[last:m[0]]) + "([^/]+)"
+		names = append(names, path[m[2]:m[3]])
+		last = m[1]
+	}
+	pattern += regexp.QuoteMeta(path[last:]) + "/?$"
+	return regexp.MustCompile(pattern), names, len(path) - len(strings.Join(rxTemplate.FindAllString(path, -1), ""))
+}
+
+// serverPaths the base paths of the servers, "https://api.example.com/v1" is "/v1"
+func serverPaths(servers any) []string {
+	list, _ := servers.([]any)
+	var paths []string
+	for _, s := range list {
+		m, _ := s.(map[string]any)
+		raw, _ := m["url"].(string)
+		u, err := url.Parse(raw)
+		if err != nil {
+			continue
+		}
+		if p := strings.TrimSuffix(u.EscapedPath(), "/"); p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// normalize yaml maps so that every key is a string
+func normalize(v any) any {
+	switch x := v.(type) {
+	case map[string]any:
+		for k, e := range x {
+			x[k] = normalize(e)
+		}
+		return x
+	case map[any]any:
+		m := make(map[string]any, len(x))
+		for k, e := range x {
+			m[fmt.Sprint(k)] = normalize(e)
+		}
+		return m
+	case []any:
+		for i, e := range x {
+			x[i] = normalize(e)
+		}
+		return x
+	}
+	return v
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,129 @@
+package openapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const document = `{
+	"openapi": "3.1.0",
+	"servers": [{"url": "https://api.example.com/v1/"}],
+	"paths": {
+		"/files/{name}.{ext}": {"get": {"operationId": "file", "responses": {"200": {"description": "ok"}}}},
+		"/users/{id}": {
+			"get": {
+				"parameters": [
+					{"name": "id", "in": "path", "required": true, "schema": {"type": "string"}},
+					{"name": "fields", "in": "query", "explode": false, "schema": {"type": "array", "items": {"type": "integer"}}},
+					{"name": "X-Trace", "in": "header", "required": true, "schema": {"type": "string"}}
+				],
+				"responses": {"2XX": {"description": "ok", "headers": {"ETag": {"required": true, "schema": {"type": "string"}}}}}
+			}
+		},
+		"/users/me": {"get": {"responses": {"default": {"description": "ok"}}}}
+	}
+}`
+
+func Test_find_prefers_literal_paths_and_strips_the_server_path(t *testing.T) {
+	doc, err := Parse([]byte(document))
+	require.NoError(t, err)
+
+	op, params, _ := doc.Find(http.MethodGet, "/v1/users/me")
+	require.NotNil(t, op)
+	assert.Equal(t, "/users/me", op.Path)
+	assert.Empty(t, params)
+
+	op, params, _ = doc.Find(http.MethodGet, "/users/a%2Fb")
+	require.NotNil(t, op)
+	assert.Equal(t, "/users/{id}", op.Path)
+	assert.Equal(t, map[string]string{"id": "a/b"}, params)
+
+	op, params, _ = doc.Find(http.MethodGet, "/files/report.pdf")
+	require.NotNil(t, op)
+	assert.Equal(t, "file", op.ID)
+	assert.Equal(t, map[string]string{"name": "report", "ext": "pdf"}, params)
+
+	op, _, allowed := doc.Find(http.MethodPost, "/users/me")
+	assert.Nil(t, op)
+	assert.Equal(t, []string{"GET"}, allowed)
+
+	ops := doc.Operations()
+	require.Len(t, ops, 3)
+	assert.Equal(t, "GET /files/{name}.{ext}", ops[0].String())
+}
+
+func Test_parameters_are_converted_to_the_schema_type(t *testing.T) {
+	doc, err := Parse([]byte(document))
+	require.NoError(t, err)
+	op, params, _ := doc.Find(http.MethodGet, "/users/1")
+	require.NotNil(t, op)
+
+	req := httptest.NewRequest(http.MethodGet, "/users/1?fields=1,x", nil)
+	violations := doc.ValidateRequest(op, params, req, nil)
+
+	assert.Equal(t, []string{
+		"query parameter 'fields': item 1 expected integer, got 'x'",
+		"header parameter 'X-Trace': is required",
+	}, violationStrings(violations))
+
+	violations = doc.ValidateResponse(op, http.StatusAccepted, http.Header{}, nil)
+	assert.Equal(t, []string{"response header 'ETag': is required"}, violationStrings(violations))
+}
+
+func Test_parameter_numbers_keep_every_digit_and_types_may_be_a_list(t *testing.T) {
+	doc, err := Parse([]byte(`{
+		"openapi": "3.1.0",
+		"paths": {
+			"/items": {
+				"get": {
+					"parameters": [
+						{"name": "ids", "in": "query", "schema": {"type": "array", "items": {"type": "integer", "maximum": 9007199254740992}}},
+						{"name": "limit", "in": "query", "schema": {"type": ["integer", "null"], "maximum": 10}},
+						{"name": "tag", "in": "query", "schema": {"type": ["integer", "string"], "minLength": 2}}
+					],
+					"responses": {"200": {"description": "ok"}}
+				}
+			}
+		}
+	}`))
+	require.NoError(t, err)
+	op, params, _ := doc.Find(http.MethodGet, "/items")
+	require.NotNil(t, op)
+
+	req := httptest.NewRequest(http.MethodGet, "/items?ids=9007199254740992&ids=9007199254740993&limit=11&tag=a", nil)
+	violations := doc.ValidateRequest(op, params, req, nil)
+
+	assert.Equal(t, []string{
+		"query parameter 'ids' /1: must be <= 9007199254740992",
+		"query parameter 'limit': must be <= 10",
+		"query parameter 'tag': length must be >= 2",
+	}, violationStrings(violations))
+
+	req = httptest.NewRequest(http.MethodGet, "/items?limit=x", nil)
+	violations = doc.ValidateRequest(op, params, req, nil)
+
+	assert.Equal(t, []string{"query parameter 'limit': expected integer or null, got 'x'"}, violationStrings(violations))
+}
+
+func Test_parse_errors(t *testing.T) {
+	_, err := Parse([]byte(`swagger: "2.0"`))
+	assert.ErrorContains(t, err, "unsupported openapi version")
+
+	_, err = Parse([]byte(`[1, 2]`))
+	assert.ErrorContains(t, err, "document must be an object")
+
+	_, err = Parse([]byte("openapi: 3.0.0\npaths:\n  /x: 1\n"))
+	assert.ErrorContains(t, err, "path '/x' is not an object")
+}
+
+func violationStrings(violations []Violation) []string {
+	var result []string
+	for _, v := range violations {
+		result = append(result, v.String())
+	}
+	return result
+}
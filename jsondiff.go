@@ -0,0 +1,327 @@
+package httptestclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// JSONMatchOption configures ExpectJSONMatches
+type JSONMatchOption func(*jsonMatchConfig)
+
+type jsonMatchConfig struct {
+	ignore           [][]selector
+	ignoreArrayOrder bool
+	tolerance        float64
+	err              error
+}
+
+// IgnorePaths are not compared, e.g. "$.id", "$.items[*].createdAt", recursive descent, filters and negative
+// indexes are not supported
+func IgnorePaths(paths ...string) JSONMatchOption {
+	return func(c *jsonMatchConfig) {
+		for _, p := range paths {
+			sels, err := compileIgnorePath(p)
+			if err != nil {
+				c.err = fmt.Errorf("invalid ignore path '%s': %w", p, err)
+				return
+			}
+			c.ignore = append(c.ignore, sels)
+		}
+	}
+}
+
+// IgnoreArrayOrder items may appear in any order, every expected item must match exactly one actual item.
+// Items are paired so that as many as possible match, which matters when ignored paths or a tolerance allow
+// an item to match more than one other
+func IgnoreArrayOrder() JSONMatchOption {
+	return func(c *jsonMatchConfig) {
+		c.ignoreArrayOrder = true
+	}
+}
+
+// NumericTolerance numbers match when they differ by no more than delta
+func NumericTolerance(delta float64) JSONMatchOption {
+	return func(c *jsonMatchConfig) {
+		c.tolerance = delta
+	}
+}
+
+// ExpectJSONMatches the body, expected may be a json string, []byte or any value that can be marshalled.
+// Every difference is reported with its path, e.g. "$.items[0].name: expected "a", got "b""
+func (r SimpleResponse) ExpectJSONMatches(expected any, opts ...JSONMatchOption) SimpleResponse {
+	if r.t == nil {
+		return r
+	}
+	if h, ok := r.t.(testingHooks); ok {
+		h.Helper()
+	}
+	var cfg jsonMatchConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.err != nil {
+		r.t.Errorf("%v", cfg.err)
+		return r
+	}
+	want, err := parseExpectedJSON(expected)
+	if err != nil {
+		r.t.Errorf("expected value is not valid json: %v", err)
+		return r
+	}
+	got, err := decodeJSON([]byte(r.Body), true)
+	if err != nil {
+		r.t.Errorf("body is not valid json: %v", err)
+		return r
+	}
+	if diffs := diffJSON(want, got, cfg); len(diffs) > 0 {
+		r.t.Errorf("json does not match:\n%s", strings.Join(diffs, "\n"))
+	}
+	return r
+}
+
+// parseExpectedJSON from a json document or a value to be marshalled, numbers are json.Number
+func parseExpectedJSON(expected any) (any, error) {
+	var buf []byte
+	switch v := expected.(type) {
+	case string:
+		buf = []byte(v)
+	case []byte:
+		buf = v
+	case json.RawMessage:
+		buf = v
+	default:
+		var err error
+		if buf, err = json.Marshal(expected); err != nil {
+			return nil, err
+		}
+	}
+	return decodeJSON(buf, true)
+}
+
+// compileIgnorePath to a list of selectors, each matching a single key or index
+func compileIgnorePath(path string) ([]selector, error) {
+	jp, err := compileJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+	var sels []selector
+	for _, seg := range jp.segments {
+		if seg.recursive || len(seg.selectors) != 1 {
+			return nil, fmt.Errorf("only names, indexes and wildcards are supported")
+		}
+		switch sel := seg.selectors[0].(type) {
+		case indexSelector:
+			if sel < 0 {
+				// the expected and actual arrays may differ in length, so which item is meant is ambiguous
+				return nil, fmt.Errorf("negative indexes are not supported")
+			}
+			sels = append(sels, sel)
+		case nameSelector, wildcardSelector:
+			sels = append(sels, seg.selectors[0])
+		default:
+			return nil, fmt.Errorf("only names, indexes and wildcards are supported")
+		}
+	}
+	return sels, nil
+}
+
+// diffJSON lists every difference between expected and actual as "path: description"
+func diffJSON(expected, actual any, cfg jsonMatchConfig) []string {
+	d := jsonDiffer{cfg: cfg}
+	d.compare(nil, expected, actual)
+	return d.diffs
+}
+
+type jsonDiffer struct {
+	cfg   jsonMatchConfig
+	diffs []string
+}
+
+// pathToken a key (string) or an index (int)
+type pathToken any
+
+func (d *jsonDiffer) add(path []pathToken, format string, args ...any) {
+	d.diffs = append(d.diffs, formatJSONPath(path)+": "+fmt.Sprintf(format, args...))
+}
+
+func (d *jsonDiffer) ignored(path []pathToken) bool {
+	for _, sels := range d.cfg.ignore {
+		if len(sels) != len(path) {
+			continue
+		}
+		match := true
+		for i, sel := range sels {
+			switch s := sel.(type) {
+			case nameSelector:
+				match = match && path[i] == pathToken(string(s))
+			case indexSelector:
+				match = match && path[i] == pathToken(int(s))
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *jsonDiffer) compare(path []pathToken, expected, actual any) {
+	if d.ignored(path) {
+		return
+	}
+	et, at := jsonTypeName(expected), jsonTypeName(actual)
+	if et != at {
+		d.add(path, "type changed, expected %s %s, got %s %s", et, jsonString(expected), at, jsonString(actual))
+		return
+	}
+	switch e := expected.(type) {
+	case map[string]any:
+		a := actual.(map[string]any)
+		for _, k := range sortedKeys(e) {
+			p := append(path[:len(path):len(path)], k)
+			if av, ok := a[k]; ok {
+				d.compare(p, e[k], av)
+			} else if !d.ignored(p) {
+				d.add(p, "missing key")
+			}
+		}
+		for _, k := range sortedKeys(a) {
+			p := append(path[:len(path):len(path)], k)
+			if _, ok := e[k]; !ok && !d.ignored(p) {
+				d.add(p, "unexpected key with value %s", jsonString(a[k]))
+			}
+		}
+	case []any:
+		a := actual.([]any)
+		if d.cfg.ignoreArrayOrder {
+			d.compareUnordered(path, e, a)
+			return
+		}
+		if len(e) != len(a) {
+			d.add(path, "expected %d items, got %d", len(e), len(a))
+		}
+		for i := 0; i < min(len(e), len(a)); i++ {
+			d.compare(append(path[:len(path):len(path)], i), e[i], a[i])
+		}
+	case float64, json.Number:
+		if !d.equalNumbers(e, actual) {
+			d.add(path, "expected %s, got %s", jsonString(expected), jsonString(actual))
+		}
+	default:
+		if !reflect.DeepEqual(expected, actual) {
+			d.add(path, "expected %s, got %s", jsonString(expected), jsonString(actual))
+		}
+	}
+}
+
+// equalNumbers exactly, or within the tolerance
+func (d *jsonDiffer) equalNumbers(expected, actual any) bool {
+	if d.cfg.tolerance == 0 {
+		return equalJSON(expected, actual)
+	}
+	e, ok := bigNumber(expected)
+	a, ok2 := bigNumber(actual)
+	if !ok || !ok2 {
+		return false
+	}
+	delta, _ := new(big.Float).Sub(e, a).Float64()
+	return math.Abs(delta) <= d.cfg.tolerance
+}
+
+// compareUnordered pairs expected and actual items that match exactly, using a maximum bipartite matching
+func (d *jsonDiffer) compareUnordered(path []pathToken, expected, actual []any) {
+	matches := make([][]bool, len(expected))
+	for i, e := range expected {
+		p := append(path[:len(path):len(path)], i)
+		matches[i] = make([]bool, len(actual))
+		for j, a := range actual {
+			sub := jsonDiffer{cfg: d.cfg}
+			sub.compare(p, e, a)
+			matches[i][j] = len(sub.diffs) == 0
+		}
+	}
+	// pairedWith the expected index of each actual item, -1 when unused
+	pairedWith := make([]int, len(actual))
+	for j := range pairedWith {
+		pairedWith[j] = -1
+	}
+	// augment finds an actual item for expected i, moving earlier pairs to other items when needed
+	var augment func(i int, seen []bool) bool
+	augment = func(i int, seen []bool) bool {
+		for j := range actual {
+			if !matches[i][j] || seen[j] {
+				continue
+			}
+			seen[j] = true
+			if pairedWith[j] < 0 || augment(pairedWith[j], seen) {
+				pairedWith[j] = i
+				return true
+			}
+		}
+		return false
+	}
+	for i, e := range expected {
+		p := append(path[:len(path):len(path)], i)
+		if !augment(i, make([]bool, len(actual))) && !d.ignored(p) {
+			d.add(p, "no matching item for %s", jsonString(e))
+		}
+	}
+	for j, a := range actual {
+		if pairedWith[j] < 0 {
+			d.add(path, "unexpected item %s", jsonString(a))
+		}
+	}
+}
+
+func jsonTypeName(v any) string {
+	switch v.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case float64, json.Number:
+		return "number"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func jsonString(v any) string {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(buf)
+}
+
+var rxJSONPathName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// formatJSONPath in normalized form, $.name, $['odd name'], $.list[0]
+func formatJSONPath(path []pathToken) string {
+	var sb strings.Builder
+	sb.WriteString("$")
+	for _, tok := range path {
+		switch v := tok.(type) {
+		case int:
+			sb.WriteString("[" + strconv.Itoa(v) + "]")
+		case string:
+			if rxJSONPathName.MatchString(v) {
+				sb.WriteString("." + v)
+			} else {
+				sb.WriteString("['" + strings.ReplaceAll(strings.ReplaceAll(v, `\`, `\\`), `'`, `\'`) + "']")
+			}
+		}
+	}
+	return sb.String()
+}
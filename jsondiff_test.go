@@ -0,0 +1,109 @@
+package httptestclient
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_json_differences_are_listed_by_path(t *testing.T) {
+	testData := []struct {
+		name     string
+		expected string
+		actual   string
+		opts     []JSONMatchOption
+		diffs    []string
+	}{
+		{"identical", `{"a":[1,{"b":null}]}`, `{"a":[1,{"b":null}]}`, nil, nil},
+		{"value changed", `{"a":1,"b":"x"}`, `{"a":2,"b":"x"}`, nil,
+			[]string{`$.a: expected 1, got 2`}},
+		{"missing and unexpected keys", `{"a":1,"odd key":2}`, `{"a":1,"c":3}`, nil,
+			[]string{`$['odd key']: missing key`, `$.c: unexpected key with value 3`}},
+		{"type change", `{"a":{"b":"1"}}`, `{"a":{"b":1}}`, nil,
+			[]string{`$.a.b: type changed, expected string "1", got number 1`}},
+		{"array length", `{"a":[1,2,3]}`, `{"a":[1,5]}`, nil,
+			[]string{`$.a: expected 3 items, got 2`, `$.a[1]: expected 2, got 5`}},
+		{"root", `[1]`, `{}`, nil,
+			[]string{`$: type changed, expected array [1], got object {}`}},
+		{"ignored paths", `{"id":1,"items":[{"at":1,"v":1},{"at":2,"v":2}]}`, `{"id":9,"items":[{"at":3,"v":1},{"v":2}],"x":1}`,
+			[]JSONMatchOption{IgnorePaths("$.id", "$.items[*].at", "$.x")}, nil},
+		{"ignored array index", `{"a":[1,2]}`, `{"a":[9,2]}`,
+			[]JSONMatchOption{IgnorePaths("$.a[0]")}, nil},
+		{"array order", `{"a":[1,2,{"b":3}]}`, `{"a":[{"b":3},2,1]}`,
+			[]JSONMatchOption{IgnoreArrayOrder()}, nil},
+		{"array order with differences", `{"a":[1,2,3]}`, `{"a":[3,1,4]}`,
+			[]JSONMatchOption{IgnoreArrayOrder()},
+			[]string{`$.a[1]: no matching item for 2`, `$.a: unexpected item 4`}},
+		{"array order with overlapping items", `{"a":[1.0,1.1]}`, `{"a":[1.08,0.95]}`,
+			[]JSONMatchOption{IgnoreArrayOrder(), NumericTolerance(0.1)}, nil},
+		{"array order with partly ignored items", `{"a":[{"k":1},{"k":1,"v":2}]}`, `{"a":[{"k":1,"v":2},{"k":1,"v":3}]}`,
+			[]JSONMatchOption{IgnoreArrayOrder(), IgnorePaths("$.a[0].v")}, nil},
+		{"numeric tolerance", `{"a":1.0,"b":[2.5]}`, `{"a":1.05,"b":[2.45]}`,
+			[]JSONMatchOption{NumericTolerance(0.1)}, nil},
+		{"outside numeric tolerance", `{"a":1.0}`, `{"a":1.5}`,
+			[]JSONMatchOption{NumericTolerance(0.1)},
+			[]string{`$.a: expected 1.0, got 1.5`}},
+		{"numbers by value", `{"a":1.0,"b":1e2}`, `{"a":1,"b":100}`, nil, nil},
+		{"large integers", `{"id":9007199254740993}`, `{"id":9007199254740992}`, nil,
+			[]string{`$.id: expected 9007199254740993, got 9007199254740992`}},
+	}
+	for _, td := range testData {
+		t.Run(td.name, func(t *testing.T) {
+			expected, err := decodeJSON([]byte(td.expected), true)
+			require.NoError(t, err)
+			actual, err := decodeJSON([]byte(td.actual), true)
+			require.NoError(t, err)
+			var cfg jsonMatchConfig
+			for _, opt := range td.opts {
+				opt(&cfg)
+			}
+			require.NoError(t, cfg.err)
+
+			assert.Equal(t, td.diffs, diffJSON(expected, actual, cfg))
+		})
+	}
+}
+
+func Test_response_json_can_be_matched(t *testing.T) {
+	t.Run("expected value can be a struct", func(t *testing.T) {
+		var errs []string
+		newTestResponse(&errs, `{"name":"bob","id":7}`, http.Header{}).
+			ExpectJSONMatches(struct {
+				Name string `json:"name"`
+			}{Name: "bob"}, IgnorePaths("$.id"))
+
+		assert.Empty(t, errs)
+	})
+	t.Run("differences are reported together", func(t *testing.T) {
+		var errs []string
+		newTestResponse(&errs, `{"name":"bob","id":7}`, http.Header{}).
+			ExpectJSONMatches(`{"name":"alice","id":"7"}`)
+
+		assert.Equal(t, []string{"json does not match:\n" +
+			`$.id: type changed, expected string "7", got number 7` + "\n" +
+			`$.name: expected "alice", got "bob"`}, errs)
+	})
+	t.Run("large integers are compared exactly", func(t *testing.T) {
+		var errs []string
+		newTestResponse(&errs, `{"id":9007199254740993}`, http.Header{}).
+			ExpectJSONMatches(map[string]any{"id": int64(9007199254740992)})
+
+		assert.Equal(t, []string{"json does not match:\n$.id: expected 9007199254740992, got 9007199254740993"}, errs)
+	})
+	t.Run("unsupported ignore paths fail", func(t *testing.T) {
+		var errs []string
+		newTestResponse(&errs, `{}`, http.Header{}).
+			ExpectJSONMatches(`{}`, IgnorePaths("$..id"))
+
+		assert.Equal(t, []string{"invalid ignore path '$..id': only names, indexes and wildcards are supported"}, errs)
+	})
+	t.Run("negative ignore indexes fail", func(t *testing.T) {
+		var errs []string
+		newTestResponse(&errs, `{"a":[1]}`, http.Header{}).
+			ExpectJSONMatches(`{"a":[2]}`, IgnorePaths("$.a[-1]"))
+
+		assert.Equal(t, []string{"invalid ignore path '$.a[-1]': negative indexes are not supported"}, errs)
+	})
+}
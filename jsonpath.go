@@ -0,0 +1,749 @@
+package httptestclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// jsonPath compiled expression, supports
+//
+//	$.name $['name'] $.list[0] $.list[-1] $.list[0,2] $.list[1:3] $.* $..name $.list[?(@.id > 2 && @.name == 'x')]
+type jsonPath struct {
+	segments []pathSegment
+}
+
+type pathSegment struct {
+	recursive bool
+	selectors []selector
+}
+
+// located value within a document, set replaces the value in its parent
+type located struct {
+	value any
+	set   func(v any)
+}
+
+type selector interface {
+	// selectFrom node appending all matches to out
+	selectFrom(root any, node located, out []located) []located
+	// definite when at most one value can be selected
+	definite() bool
+}
+
+// compileJSONPath expression, the expression must start with '$'
+func compileJSONPath(expr string) (*jsonPath, error) {
+	p := &pathParser{src: expr}
+	p.skipSpace()
+	if !p.consume("$") {
+		return nil, errors.New("must start with '$'")
+	}
+	segments, err := p.segments()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if !p.eof() {
+		return nil, fmt.Errorf("unexpected '%s' at %d", p.src[p.pos:], p.pos)
+	}
+	return &jsonPath{segments: segments}, nil
+}
+
+// evaluate against a document decoded by encoding/json
+func (jp *jsonPath) evaluate(doc any) []any {
+	return values(evalSegments(doc, doc, jp.segments))
+}
+
+// replace every selected value, the document is modified in place, the updated document is returned
+func (jp *jsonPath) replace(doc any, value any) any {
+	root := located{value: doc, set: func(v any) { doc = v }}
+	for _, n := range evalLocated(doc, root, jp.segments) {
+		n.set(value)
+	}
+	return doc
+}
+
+// definite when the path can only ever select a single value
+func (jp *jsonPath) definite() bool {
+	for _, s := range jp.segments {
+		if s.recursive || len(s.selectors) != 1 || !s.selectors[0].definite() {
+			return false
+		}
+	}
+	return true
+}
+
+func evalSegments(root, node any, segments []pathSegment) []located {
+	return evalLocated(root, located{value: node, set: func(any) {}}, segments)
+}
+
+func evalLocated(root any, node located, segments []pathSegment) []located {
+	nodes := []located{node}
+	for _, seg := range segments {
+		var next []located
+		for _, n := range nodes {
+			candidates := []located{n}
+			if seg.recursive {
+				candidates = descendants(n, nil)
+			}
+			for _, c := range candidates {
+				for _, sel := range seg.selectors {
+					next = sel.selectFrom(root, c, next)
+				}
+			}
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+func values(nodes []located) []any {
+	var out []any
+	for _, n := range nodes {
+		out = append(out, n.value)
+	}
+	return out
+}
+
+// descendants including node itself, object members are visited in key order so results are stable
+func descendants(node located, out []located) []located {
+	out = append(out, node)
+	for _, child := range children(node.value) {
+		out = descendants(child, out)
+	}
+	return out
+}
+
+// children of an object, in key order, or an array
+func children(node any) []located {
+	var out []located
+	switch v := node.(type) {
+	case map[string]any:
+		for _, k := range sortedKeys(v) {
+			out = append(out, memberOf(v, k))
+		}
+	case []any:
+		for i := range v {
+			out = append(out, elementOf(v, i))
+		}
+	}
+	return out
+}
+
+func memberOf(m map[string]any, key string) located {
+	return located{value: m[key], set: func(v any) { m[key] = v }}
+}
+
+func elementOf(a []any, i int) located {
+	return located{value: a[i], set: func(v any) { a[i] = v }}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+type nameSelector string
+
+func (s nameSelector) selectFrom(_ any, node located, out []located) []located {
+	if m, ok := node.value.(map[string]any); ok {
+		if _, ok := m[string(s)]; ok {
+			out = append(out, memberOf(m, string(s)))
+		}
+	}
+	return out
+}
+func (s nameSelector) definite() bool { return true }
+
+type wildcardSelector struct{}
+
+func (wildcardSelector) selectFrom(_ any, node located, out []located) []located {
+	return append(out, children(node.value)...)
+}
+func (wildcardSelector) definite() bool { return false }
+
+type indexSelector int
+
+func (s indexSelector) selectFrom(_ any, node located, out []located) []located {
+	if a, ok := node.value.([]any); ok {
+		i := int(s)
+		if i < 0 {
+			i += len(a)
+		}
+		if i >= 0 && i < len(a) {
+			out = append(out, elementOf(a, i))
+		}
+	}
+	return out
+}
+func (s indexSelector) definite() bool { return true }
+
+type sliceSelector struct {
+	start, end *int
+	step       int
+}
+
+func (s sliceSelector) selectFrom(_ any, node located, out []located) []located {
+	a, ok := node.value.([]any)
+	if !ok || s.step == 0 {
+		return out
+	}
+	n := len(a)
+	bound := func(v *int, def int) int {
+		if v == nil {
+			return def
+		}
+		i := *v
+		if i < 0 {
+			i += n
+		}
+		return min(max(i, -1), n)
+	}
+	if s.step > 0 {
+		for i := max(bound(s.start, 0), 0); i < bound(s.end, n); i += s.step {
+			out = append(out, elementOf(a, i))
+		}
+	} else {
+		for i := min(bound(s.start, n-1), n-1); i > bound(s.end, -1); i += s.step {
+			out = append(out, elementOf(a, i))
+		}
+	}
+	return out
+}
+func (s sliceSelector) definite() bool { return false }
+
+type filterSelector struct {
+	expr filterExpr
+}
+
+func (s filterSelector) selectFrom(root any, node located, out []located) []located {
+	for _, child := range children(node.value) {
+		if s.expr.test(root, child.value) {
+			out = append(out, child)
+		}
+	}
+	return out
+}
+func (s filterSelector) definite() bool { return false }
+
+// filterExpr used within [?( )]
+type filterExpr interface {
+	test(root, current any) bool
+}
+
+type logicalExpr struct {
+	and         bool
+	left, right filterExpr
+}
+
+func (e logicalExpr) test(root, current any) bool {
+	if e.and {
+		return e.left.test(root, current) && e.right.test(root, current)
+	}
+	return e.left.test(root, current) || e.right.test(root, current)
+}
+
+type notExpr struct {
+	expr filterExpr
+}
+
+func (e notExpr) test(root, current any) bool { return !e.expr.test(root, current) }
+
+// operand of a comparison, either a literal or a path relative to '@' or '$'
+type operand struct {
+	literal  any
+	path     []pathSegment
+	relative bool
+	isPath   bool
+}
+
+// values selected, a literal always has exactly one
+func (o operand) values(root, current any) []any {
+	if !o.isPath {
+		return []any{o.literal}
+	}
+	if o.relative {
+		return values(evalSegments(root, current, o.path))
+	}
+	return values(evalSegments(root, root, o.path))
+}
+
+type existsExpr struct {
+	operand operand
+}
+
+func (e existsExpr) test(root, current any) bool {
+	v := e.operand.values(root, current)
+	if !e.operand.isPath {
+		return len(v) == 1 && v[0] != nil && v[0] != false
+	}
+	return len(v) > 0
+}
+
+type compareExpr struct {
+	op          string
+	left, right operand
+	rx          *regexp.Regexp
+}
+
+func (e compareExpr) test(root, current any) bool {
+	left := e.left.values(root, current)
+	right := e.right.values(root, current)
+	if len(left) != 1 || len(right) != 1 {
+		// nothing selected, only "not equal" to something is true
+		return e.op == "!=" && len(left) != len(right)
+	}
+	l, r := numberValue(left[0]), numberValue(right[0])
+	switch e.op {
+	case "==":
+		return reflect.DeepEqual(l, r)
+	case "!=":
+		return !reflect.DeepEqual(l, r)
+	case "=~":
+		s, ok := l.(string)
+		return ok && e.rx != nil && e.rx.MatchString(s)
+	}
+	cmp, ok := compareOrdered(l, r)
+	if !ok {
+		return false
+	}
+	switch e.op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
+
+// numberValue json.Number as float64 so that it can be compared with literals
+func numberValue(v any) any {
+	if n, ok := v.(json.Number); ok {
+		if f, err := n.Float64(); err == nil {
+			return f
+		}
+	}
+	return v
+}
+
+// compareOrdered numbers or strings, false if the values cannot be ordered
+func compareOrdered(l, r any) (int, bool) {
+	switch lv := l.(type) {
+	case float64:
+		if rv, ok := r.(float64); ok {
+			switch {
+			case lv < rv:
+				return -1, true
+			case lv > rv:
+				return 1, true
+			}
+			return 0, true
+		}
+	case string:
+		if rv, ok := r.(string); ok {
+			return strings.Compare(lv, rv), true
+		}
+	}
+	return 0, false
+}
+
+// pathParser hand written recursive descent parser for json path expressions
+type pathParser struct {
+	src string
+	pos int
+}
+
+func (p *pathParser) eof() bool { return p.pos >= len(p.src) }
+
+func (p *pathParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *pathParser) skipSpace() {
+	for !p.eof() && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+func (p *pathParser) consume(s string) bool {
+	if strings.HasPrefix(p.src[p.pos:], s) {
+		p.pos += len(s)
+		return true
+	}
+	return false
+}
+
+// segments until a character that cannot start a segment
+func (p *pathParser) segments() ([]pathSegment, error) {
+	var segments []pathSegment
+	for {
+		switch {
+		case p.consume(".."):
+			seg, err := p.dotSegment()
+			if err != nil {
+				return nil, err
+			}
+			seg.recursive = true
+			segments = append(segments, seg)
+		case p.consume("."):
+			seg, err := p.dotSegment()
+			if err != nil {
+				return nil, err
+			}
+			segments = append(segments, seg)
+		case p.consume("["):
+			sels, err := p.bracket()
+			if err != nil {
+				return nil, err
+			}
+			segments = append(segments, pathSegment{selectors: sels})
+		default:
+			return segments, nil
+		}
+	}
+}
+
+// dotSegment after '.' or '..', a bracket is allowed after '..'
+func (p *pathParser) dotSegment() (pathSegment, error) {
+	if p.consume("*") {
+		return pathSegment{selectors: []selector{wildcardSelector{}}}, nil
+	}
+	if p.consume("[") {
+		sels, err := p.bracket()
+		return pathSegment{selectors: sels}, err
+	}
+	start := p.pos
+	for !p.eof() && isNameChar(p.peek()) {
+		p.pos++
+	}
+	if start == p.pos {
+		return pathSegment{}, fmt.Errorf("expected name at %d", start)
+	}
+	return pathSegment{selectors: []selector{nameSelector(p.src[start:p.pos])}}, nil
+}
+
+func isNameChar(b byte) bool {
+	return b == '_' || b == '-' || b == '$' || b >= 0x80 ||
+		(b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9')
+}
+
+// bracket selectors after '[' up to and including ']'
+func (p *pathParser) bracket() ([]selector, error) {
+	var sels []selector
+	for {
+		p.skipSpace()
+		sel, err := p.bracketSelector()
+		if err != nil {
+			return nil, err
+		}
+		sels = append(sels, sel)
+		p.skipSpace()
+		if p.consume("]") {
+			return sels, nil
+		}
+		if !p.consume(",") {
+			return nil, fmt.Errorf("expected ',' or ']' at %d", p.pos)
+		}
+	}
+}
+
+func (p *pathParser) bracketSelector() (selector, error) {
+	switch c := p.peek(); {
+	case c == '*':
+		p.pos++
+		return wildcardSelector{}, nil
+	case c == '\'' || c == '"':
+		s, err := p.quoted()
+		return nameSelector(s), err
+	case c == '?':
+		p.pos++
+		p.skipSpace()
+		expr, err := p.filterOr()
+		return filterSelector{expr: expr}, err
+	default:
+		return p.indexOrSlice()
+	}
+}
+
+func (p *pathParser) indexOrSlice() (selector, error) {
+	var parts [3]*int
+	n := 0
+	for {
+		p.skipSpace()
+		if i, ok := p.integer(); ok {
+			parts[n] = &i
+		}
+		p.skipSpace()
+		if n < 2 && p.consume(":") {
+			n++
+			continue
+		}
+		break
+	}
+	if n == 0 {
+		if parts[0] == nil {
+			return nil, fmt.Errorf("expected index at %d", p.pos)
+		}
+		return indexSelector(*parts[0]), nil
+	}
+	step := 1
+	if parts[2] != nil {
+		step = *parts[2]
+	}
+	return sliceSelector{start: parts[0], end: parts[1], step: step}, nil
+}
+
+func (p *pathParser) integer() (int, bool) {
+	start := p.pos
+	if p.peek() == '-' {
+		p.pos++
+	}
+	for !p.eof() && p.peek() >= '0' && p.peek() <= '9' {
+		p.pos++
+	}
+	i, err := strconv.Atoi(p.src[start:p.pos])
+	if err != nil {
+		p.pos = start
+		return 0, false
+	}
+	return i, true
+}
+
+// quoted string using either ' or ", backslash escapes the next character
+func (p *pathParser) quoted() (string, error) {
+	q := p.peek()
+	p.pos++
+	var sb strings.Builder
+	for !p.eof() {
+		c := p.peek()
+		p.pos++
+		switch {
+		case c == '\\' && !p.eof():
+			sb.WriteByte(p.peek())
+			p.pos++
+		case c == q:
+			return sb.String(), nil
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return "", errors.New("unterminated string")
+}
+
+func (p *pathParser) filterOr() (filterExpr, error) {
+	left, err := p.filterAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		p.skipSpace()
+		if !p.consume("||") {
+			return left, nil
+		}
+		right, err := p.filterAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = logicalExpr{left: left, right: right}
+	}
+}
+
+func (p *pathParser) filterAnd() (filterExpr, error) {
+	left, err := p.filterUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		p.skipSpace()
+		if !p.consume("&&") {
+			return left, nil
+		}
+		right, err := p.filterUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = logicalExpr{and: true, left: left, right: right}
+	}
+}
+
+func (p *pathParser) filterUnary() (filterExpr, error) {
+	p.skipSpace()
+	if p.consume("!") {
+		expr, err := p.filterUnary()
+		return notExpr{expr: expr}, err
+	}
+	if p.consume("(") {
+		expr, err := p.filterOr()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if !p.consume(")") {
+			return nil, fmt.Errorf("expected ')' at %d", p.pos)
+		}
+		return expr, nil
+	}
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	for _, op := range []string{"==", "!=", "<=", ">=", "=~", "<", ">"} {
+		if !p.consume(op) {
+			continue
+		}
+		p.skipSpace()
+		right, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		expr := compareExpr{op: op, left: left, right: right}
+		if op == "=~" {
+			pattern, ok := right.literal.(string)
+			if !ok {
+				return nil, errors.New("=~ requires a string pattern")
+			}
+			if expr.rx, err = regexp.Compile(pattern); err != nil {
+				return nil, err
+			}
+		}
+		return expr, nil
+	}
+	return existsExpr{operand: left}, nil
+}
+
+func (p *pathParser) operand() (operand, error) {
+	switch c := p.peek(); {
+	case c == '@' || c == '$':
+		p.pos++
+		segments, err := p.segments()
+		return operand{path: segments, relative: c == '@', isPath: true}, err
+	case c == '\'' || c == '"':
+		s, err := p.quoted()
+		return operand{literal: s}, err
+	case c == '/':
+		// regular expression literal /pattern/
+		end := strings.IndexByte(p.src[p.pos+1:], '/')
+		if end < 0 {
+			return operand{}, errors.New("unterminated regular expression")
+		}
+		s := p.src[p.pos+1 : p.pos+1+end]
+		p.pos += end + 2
+		return operand{literal: s}, nil
+	case p.consume("true"):
+		return operand{literal: true}, nil
+	case p.consume("false"):
+		return operand{literal: false}, nil
+	case p.consume("null"):
+		return operand{literal: nil}, nil
+	}
+	start := p.pos
+	for !p.eof() && strings.IndexByte("+-.0123456789eE", p.peek()) >= 0 {
+		p.pos++
+	}
+	f, err := strconv.ParseFloat(p.src[start:p.pos], 64)
+	if err != nil {
+		return operand{}, fmt.Errorf("expected value at %d", start)
+	}
+	return operand{literal: f}, nil
+}
+
+// decodeJSON as json.Unmarshal, with useNumber numbers are json.Number so large integers keep every digit
+func decodeJSON(body []byte, useNumber bool) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	if useNumber {
+		dec.UseNumber()
+	}
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		if err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF) {
+			// as reported by json.Unmarshal
+			return nil, errors.New("unexpected end of JSON input")
+		}
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("invalid character after top-level value")
+	}
+	return doc, nil
+}
+
+// normalizeJSON converts any value to the types produced by encoding/json, numbers are json.Number
+func normalizeJSON(v any) (any, error) {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return decodeJSON(buf, true)
+}
+
+// numberPrecision in bits, enough for every digit of an id or amount, a float64 converts exactly
+const numberPrecision = 512
+
+// bigNumber of a decoded json number, a float64 or a json.Number
+func bigNumber(v any) (*big.Float, bool) {
+	switch n := v.(type) {
+	case float64:
+		if math.IsInf(n, 0) || math.IsNaN(n) {
+			return nil, false
+		}
+		return new(big.Float).SetPrec(numberPrecision).SetFloat64(n), true
+	case json.Number:
+		f, _, err := big.ParseFloat(n.String(), 10, numberPrecision, big.ToNearestEven)
+		return f, err == nil
+	}
+	return nil, false
+}
+
+// equalJSON decoded json, numbers by value so 1 and 1.0 are equal and large integers compare every digit
+func equalJSON(a, b any) bool {
+	if na, ok := bigNumber(a); ok {
+		nb, ok := bigNumber(b)
+		return ok && na.Cmp(nb) == 0
+	}
+	switch x := a.(type) {
+	case []any:
+		y, ok := b.([]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equalJSON(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]any:
+		y, ok := b.(map[string]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, v := range x {
+			if w, ok := y[k]; !ok || !equalJSON(v, w) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
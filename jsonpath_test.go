@@ -0,0 +1,102 @@
+package httptestclient
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const storeJSON = `{
+	"store": {
+		"book": [
+			{"category": "reference", "author": "Nigel Rees", "title": "Sayings of the Century", "price": 8.95},
+			{"category": "fiction", "author": "Evelyn Waugh", "title": "Sword of Honour", "price": 12.99},
+			{"category": "fiction", "author": "Herman Melville", "title": "Moby Dick", "isbn": "0-553-21311-3", "price": 8.99},
+			{"category": "fiction", "author": "J. R. R. Tolkien", "title": "The Lord of the Rings", "isbn": "0-395-19395-8", "price": 22.99}
+		],
+		"bicycle": {"color": "red", "price": 19.95},
+		"odd key": {"with.dot": true}
+	}
+}`
+
+func Test_json_path_selection(t *testing.T) {
+	var doc any
+	require.NoError(t, json.Unmarshal([]byte(storeJSON), &doc))
+
+	testData := []struct {
+		path     string
+		expected string
+		definite bool
+	}{
+		{"$", storeJSON, true},
+		{"$.store.bicycle.color", `["red"]`, true},
+		{"$['store']['bicycle'][\"price\"]", `[19.95]`, true},
+		{"$.store['odd key']['with.dot']", `[true]`, true},
+		{"$.store.book[0].author", `["Nigel Rees"]`, true},
+		{"$.store.book[-1].author", `["J. R. R. Tolkien"]`, true},
+		{"$.store.book[9].author", `[]`, true},
+		{"$.store.book[0,2].price", `[8.95, 8.99]`, false},
+		{"$.store.book[1:3].price", `[12.99, 8.99]`, false},
+		{"$.store.book[:2].price", `[8.95, 12.99]`, false},
+		{"$.store.book[::-2].price", `[22.99, 12.99]`, false},
+		{"$.store.book[*].author", `["Nigel Rees", "Evelyn Waugh", "Herman Melville", "J. R. R. Tolkien"]`, false},
+		{"$.store.bicycle.*", `["red", 19.95]`, false},
+		{"$..isbn", `["0-553-21311-3", "0-395-19395-8"]`, false},
+		{"$..bicycle.color", `["red"]`, false},
+		{"$.store.book[?(@.isbn)].title", `["Moby Dick", "The Lord of the Rings"]`, false},
+		{"$.store.book[?(!@.isbn)].price", `[8.95, 12.99]`, false},
+		{"$.store.book[?(@.price < 10)].title", `["Sayings of the Century", "Moby Dick"]`, false},
+		{"$.store.book[?@.price >= 22.99].title", `["The Lord of the Rings"]`, false},
+		{"$.store.book[?(@.category == 'fiction' && @.price < 13)].title", `["Sword of Honour", "Moby Dick"]`, false},
+		{"$.store.book[?(@.price > 20 || @.category == \"reference\")].price", `[8.95, 22.99]`, false},
+		{"$.store.book[?(@.author =~ /^J\\. /)].price", `[22.99]`, false},
+		{"$.store.book[?(@.price > $.store.bicycle.price)].price", `[22.99]`, false},
+		{"$.store.book[?(@.missing != 'x')].price", `[8.95, 12.99, 8.99, 22.99]`, false},
+	}
+	for _, td := range testData {
+		t.Run(td.path, func(t *testing.T) {
+			jp, err := compileJSONPath(td.path)
+			require.NoError(t, err)
+
+			actual := jp.evaluate(doc)
+			if actual == nil {
+				actual = []any{}
+			}
+			if td.path == "$" {
+				buf, _ := json.Marshal(actual[0])
+				assert.JSONEq(t, td.expected, string(buf))
+			} else {
+				buf, _ := json.Marshal(actual)
+				assert.JSONEq(t, td.expected, string(buf))
+			}
+			assert.Equal(t, td.definite, jp.definite())
+		})
+	}
+}
+
+func Test_invalid_json_paths_are_rejected(t *testing.T) {
+	for _, path := range []string{"", "store", "$.", "$[", "$[0", "$['open", "$[?(@.a == )]", "$[?(@.a =~ 1)]", "$.a b"} {
+		t.Run(path, func(t *testing.T) {
+			_, err := compileJSONPath(path)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func Test_json_path_values_can_be_replaced(t *testing.T) {
+	var doc any
+	require.NoError(t, json.Unmarshal([]byte(`{"a":[{"id":1},{"id":2}],"b":"x"}`), &doc))
+
+	jp, err := compileJSONPath("$..id")
+	require.NoError(t, err)
+	actual := jp.replace(doc, "-")
+
+	buf, _ := json.Marshal(actual)
+	assert.JSONEq(t, `{"a":[{"id":"-"},{"id":"-"}],"b":"x"}`, string(buf))
+
+	jp, err = compileJSONPath("$")
+	require.NoError(t, err)
+	assert.Equal(t, "root", jp.replace(doc, "root"))
+}
@@ -0,0 +1,117 @@
+package httptestclient
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"path"
+	"strings"
+)
+
+// multipartPart a field or file of a multipart/form-data body
+type multipartPart struct {
+	field       string
+	filename    string
+	contentType string
+	content     []byte
+}
+
+// MultipartField adds a field to a multipart/form-data body, can be called multiple times, replaces any other kind of body
+func (c *Client) MultipartField(name, value string) *Client {
+	return c.addMultipart(multipartPart{field: name, content: []byte(value)})
+}
+
+// MultipartFile adds a file to a multipart/form-data body, the content type is detected from the filename extension
+func (c *Client) MultipartFile(field, filename string, r io.Reader) *Client {
+	if h, ok := c.t.(testingHooks); ok {
+		h.Helper()
+	}
+	content, err := io.ReadAll(r)
+	if c.hasError(err) {
+		return c
+	}
+	return c.addMultipart(multipartPart{
+		field:       field,
+		filename:    filename,
+		contentType: contentTypeByExtension(filename),
+		content:     content,
+	})
+}
+
+// MultipartFileFromFS adds the file at name in fsys, e.g. an embed.FS or os.DirFS("testdata"), see MultipartFile
+func (c *Client) MultipartFileFromFS(field string, fsys fs.FS, name string) *Client {
+	if h, ok := c.t.(testingHooks); ok {
+		h.Helper()
+	}
+	content, err := fs.ReadFile(fsys, name)
+	if c.hasError(err) {
+		return c
+	}
+	return c.addMultipart(multipartPart{
+		field:       field,
+		filename:    path.Base(name),
+		contentType: contentTypeByExtension(name),
+		content:     content,
+	})
+}
+
+// addMultipart re-encodes every part as the body, replacing any other kind of body
+func (c *Client) addMultipart(part multipartPart) *Client {
+	if h, ok := c.t.(testingHooks); ok {
+		h.Helper()
+	}
+	c.form = nil
+	c.multipart = append(c.multipart, part)
+
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	if c.multipartBoundary == "" {
+		c.multipartBoundary = w.Boundary()
+	}
+	err := w.SetBoundary(c.multipartBoundary)
+	for _, p := range c.multipart {
+		if err != nil {
+			break
+		}
+		var pw io.Writer
+		pw, err = w.CreatePart(p.header())
+		if err == nil {
+			_, err = pw.Write(p.content)
+		}
+	}
+	if err == nil {
+		err = w.Close()
+	}
+	if c.hasError(err) {
+		return c
+	}
+	c.body = buf.Bytes()
+	c.bodyTemplate = false
+	return c
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func (p multipartPart) header() textproto.MIMEHeader {
+	h := make(textproto.MIMEHeader)
+	disposition := fmt.Sprintf(`form-data; name="%s"`, quoteEscaper.Replace(p.field))
+	if p.filename != "" {
+		disposition += fmt.Sprintf(`; filename="%s"`, quoteEscaper.Replace(p.filename))
+	}
+	h.Set("Content-Disposition", disposition)
+	if p.contentType != "" {
+		h.Set("Content-Type", p.contentType)
+	}
+	return h
+}
+
+func contentTypeByExtension(filename string) string {
+	if ct := mime.TypeByExtension(path.Ext(filename)); ct != "" {
+		return ct
+	}
+	return "application/octet-stream"
+}
@@ -0,0 +1,116 @@
+package httptestclient_test
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/NearlyUnique/httptestclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type uploadedFile struct {
+	filename    string
+	contentType string
+	content     string
+}
+
+func Test_multipart_form_posting(t *testing.T) {
+	var actual struct {
+		contentType string
+		fields      map[string][]string
+		files       map[string]uploadedFile
+	}
+	target := httptestclient.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actual.contentType = r.Header.Get("Content-Type")
+		require.NoError(t, r.ParseMultipartForm(1<<20))
+		actual.fields = r.MultipartForm.Value
+		actual.files = map[string]uploadedFile{}
+		for field, headers := range r.MultipartForm.File {
+			f, err := headers[0].Open()
+			require.NoError(t, err)
+			buf, err := io.ReadAll(f)
+			require.NoError(t, err)
+			actual.files[field] = uploadedFile{
+				filename:    headers[0].Filename,
+				contentType: headers[0].Header.Get("Content-Type"),
+				content:     string(buf),
+			}
+		}
+	}))
+	fsys := fstest.MapFS{
+		"docs/readme.txt": {Data: []byte("from fs")},
+	}
+
+	_ = httptestclient.New(t).
+		Post("/upload").
+		MultipartField("name", "value1").
+		MultipartField("name", "value2").
+		MultipartField("quoted", `a "b"`).
+		MultipartFile("avatar", "me.png", strings.NewReader("png-bytes")).
+		MultipartFile("data", "blob", strings.NewReader("raw")).
+		MultipartFileFromFS("doc", fsys, "docs/readme.txt").
+		DoSimpleTarget(target)
+
+	assert.True(t, strings.HasPrefix(actual.contentType, "multipart/form-data; boundary="), actual.contentType)
+	assert.Equal(t, map[string][]string{
+		"name":   {"value1", "value2"},
+		"quoted": {`a "b"`},
+	}, actual.fields)
+	assert.Equal(t, map[string]uploadedFile{
+		"avatar": {filename: "me.png", contentType: "image/png", content: "png-bytes"},
+		"data":   {filename: "blob", contentType: "application/octet-stream", content: "raw"},
+		"doc":    {filename: "readme.txt", contentType: "text/plain; charset=utf-8", content: "from fs"},
+	}, actual.files)
+}
+
+func Test_multipart_file_from_fs_must_exist(t *testing.T) {
+	ht := &hookedT{}
+
+	req := httptestclient.New(ht).
+		MultipartFileFromFS("doc", fstest.MapFS{}, "missing.txt").
+		BuildRequest()
+
+	assert.Nil(t, req)
+	assert.Equal(t, []string{"Expected no error, got open missing.txt: file does not exist"}, ht.errors)
+}
+
+func Test_the_last_body_setter_decides_the_kind_of_body(t *testing.T) {
+	testData := []struct {
+		name        string
+		client      func(t *testing.T) *httptestclient.Client
+		contentType string
+		body        string
+	}{
+		{"multipart then json", func(t *testing.T) *httptestclient.Client {
+			return httptestclient.New(t).MultipartField("a", "1").BodyJSON(map[string]int{"b": 2})
+		}, "application/json", `{"b":2}`},
+		{"form then string", func(t *testing.T) *httptestclient.Client {
+			return httptestclient.New(t).FormData("a", "1").BodyString("text")
+		}, "application/json", "text"},
+		{"multipart then form", func(t *testing.T) *httptestclient.Client {
+			return httptestclient.New(t).MultipartField("a", "1").FormData("b", "2")
+		}, "application/x-www-form-urlencoded", "b=2"},
+	}
+	for _, td := range testData {
+		t.Run(td.name, func(t *testing.T) {
+			req := td.client(t).Post("/").BuildRequest()
+			require.NotNil(t, req)
+			buf, err := io.ReadAll(req.Body)
+			require.NoError(t, err)
+
+			assert.Equal(t, td.contentType, req.Header.Get("Content-Type"))
+			assert.Equal(t, td.body, string(buf))
+		})
+	}
+	t.Run("form then multipart", func(t *testing.T) {
+		req := httptestclient.New(t).Post("/").FormData("a", "1").MultipartField("b", "2").BuildRequest()
+		require.NotNil(t, req)
+		require.NoError(t, req.ParseMultipartForm(1<<20))
+
+		assert.Equal(t, map[string][]string{"b": {"2"}}, req.MultipartForm.Value)
+	})
+}
@@ -0,0 +1,125 @@
+package httptestclient
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/NearlyUnique/httptestclient/internal/openapi"
+)
+
+// OpenAPI document that requests and responses are validated against, see Client.OpenAPI and Session.OpenAPI
+type OpenAPI struct {
+	doc *openapi.Document
+
+	mu        sync.Mutex
+	responses map[*openapi.Operation]map[int]int
+}
+
+// LoadOpenAPI 3 document, JSON or YAML, $ref values must be local to the document
+func LoadOpenAPI(file string) (*OpenAPI, error) {
+	doc, err := openapi.Load(file)
+	if err != nil {
+		return nil, err
+	}
+	return &OpenAPI{doc: doc}, nil
+}
+
+// OpenAPI validates every request built, and every response received, against the matching operation of doc;
+// path, query and header parameters, the request body, the response status, headers and body. Any violation
+// fails the test with the JSON pointer of the offending field. Takes precedence over the Session document
+func (c *Client) OpenAPI(doc *OpenAPI) *Client {
+	c.openAPI = doc
+	return c
+}
+
+// OpenAPI validates the traffic of every Client using the session, see Client.OpenAPI
+func (s *Session) OpenAPI(doc *OpenAPI) *Session {
+	s.openAPI = doc
+	return s
+}
+
+// openAPIFor the client, or the session when the client has none
+func (c *Client) openAPIFor(session *Session) *OpenAPI {
+	if c.openAPI != nil || session == nil {
+		return c.openAPI
+	}
+	return session.openAPI
+}
+
+// validateRequest against doc, false when the test has failed
+func (c *Client) validateRequest(doc *OpenAPI, baseURL string, req *http.Request, body []byte) bool {
+	if h, ok := c.t.(testingHooks); ok {
+		h.Helper()
+	}
+	if doc == nil {
+		return true
+	}
+	path := relativePath(baseURL, req.URL)
+	op, params, allowed := doc.doc.Find(req.Method, path)
+	if op == nil {
+		c.failNow("openapi has no operation for %s %s%s", req.Method, path, allowedMethods(allowed))
+		return false
+	}
+	if violations := doc.doc.ValidateRequest(op, params, req, body); len(violations) > 0 {
+		c.failNow("request does not match openapi %s\n%s", op, formatViolations(violations))
+		return false
+	}
+	return true
+}
+
+// validateResponse against doc, false when the test has failed
+func (c *Client) validateResponse(doc *OpenAPI, baseURL string, resp *http.Response, body []byte, redirected bool) bool {
+	if h, ok := c.t.(testingHooks); ok {
+		h.Helper()
+	}
+	if doc == nil {
+		return true
+	}
+	path := relativePath(baseURL, resp.Request.URL)
+	op, _, allowed := doc.doc.Find(resp.Request.Method, path)
+	if op == nil {
+		if redirected {
+			// redirected outside the api, e.g. to a login page
+			return true
+		}
+		c.failNow("openapi has no operation for %s %s%s", resp.Request.Method, path, allowedMethods(allowed))
+		return false
+	}
+	if violations := doc.doc.ValidateResponse(op, resp.StatusCode, resp.Header, body); len(violations) > 0 {
+		c.failNow("response %d does not match openapi %s\n%s", resp.StatusCode, op, formatViolations(violations))
+		return false
+	}
+	return true
+}
+
+// relativePath of u to the base url of the target, escaped as the document paths are
+func relativePath(baseURL string, u *url.URL) string {
+	path := u.EscapedPath()
+	if base, err := url.Parse(baseURL); err == nil {
+		if rest, ok := strings.CutPrefix(path, strings.TrimSuffix(base.EscapedPath(), "/")); ok && strings.HasPrefix(rest, "/") {
+			return rest
+		}
+	}
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+func allowedMethods(allowed []string) string {
+	if len(allowed) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(", allowed %s", strings.Join(allowed, ", "))
+}
+
+func formatViolations(violations []openapi.Violation) string {
+	lines := make([]string, len(violations))
+	for i, v := range violations {
+		lines[i] = "  " + v.String()
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,254 @@
+package httptestclient_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/NearlyUnique/httptestclient"
+	"github.com/NearlyUnique/httptestclient/internal/self"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadItemsOpenAPI(t *testing.T) *httptestclient.OpenAPI {
+	doc, err := httptestclient.LoadOpenAPI("testdata/openapi/items.yaml")
+	require.NoError(t, err)
+	return doc
+}
+
+func writeJSON(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(body))
+}
+
+// openAPIServer counts the requests it receives, some responses do not match the document
+func openAPIServer(received *int32) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/items", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(received, 1)
+		writeJSON(w, http.StatusOK, `[{"id":1,"name":"widget"},{"id":2,"name":"gadget","note":null}]`)
+	})
+	mux.HandleFunc("GET /api/items/mine", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(received, 1)
+		writeJSON(w, http.StatusOK, `[]`)
+	})
+	mux.HandleFunc("POST /api/items", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(received, 1)
+		_, _ = io.ReadAll(r.Body)
+		w.Header().Set("Location", "/api/items/1")
+		writeJSON(w, http.StatusCreated, `{"id":1,"name":"widget"}`)
+	})
+	mux.HandleFunc("GET /api/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(received, 1)
+		switch r.PathValue("id") {
+		case "1":
+			writeJSON(w, http.StatusOK, `{"id":1,"name":"widget","price":2.5}`)
+		case "2":
+			writeJSON(w, http.StatusOK, `{"id":"2","name":"","colour":"red"}`)
+		case "3":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	mux.HandleFunc("DELETE /api/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(received, 1)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return mux
+}
+
+func Test_openapi_traffic_matching_the_document_passes(t *testing.T) {
+	var received int32
+	session := httptestclient.NewSession(httptestclient.Handler(openAPIServer(&received))).
+		OpenAPI(loadItemsOpenAPI(t))
+
+	httptestclient.New(t).
+		Post("/api/items").
+		BodyString(`{"name":"widget","price":2.5}`).
+		ExpectedStatusCode(http.StatusCreated).
+		DoSimpleTarget(session)
+	httptestclient.New(t).
+		Get("/api/items").
+		Query("limit", "10", "tag", "a", "tag", "b").
+		DoSimpleTarget(session)
+	httptestclient.New(t).Get("/api/items/mine").DoSimpleTarget(session)
+	httptestclient.New(t).
+		Get("/api/items/$0", 1).
+		Header("X-Request-Id", "6f1c2d3e-4a5b-4c6d-8e9f-a0b1c2d3e4f5").
+		DoSimpleTarget(session)
+	httptestclient.New(t).Get("/api/items/$0", 9).ExpectedStatusCode(http.StatusNotFound).DoSimpleTarget(session)
+	httptestclient.New(t).Delete("/api/items/$0", 1).ExpectedStatusCode(http.StatusNoContent).DoSimpleTarget(session)
+
+	assert.Equal(t, int32(6), received)
+}
+
+func Test_openapi_paths_are_relative_to_the_target(t *testing.T) {
+	var received int32
+	server := httptestclient.Handler(openAPIServer(&received))
+
+	httptestclient.New(t).
+		OpenAPI(loadItemsOpenAPI(t)).
+		Get("/items/$0", 1).
+		DoSimpleTarget(httptestclient.BaseURL(server.BaseURL()+"/api", server.Client()))
+
+	assert.Equal(t, int32(1), received)
+}
+
+func Test_openapi_request_violations_fail_before_the_request_is_sent(t *testing.T) {
+	testCases := []struct {
+		name     string
+		client   func(c *httptestclient.Client) *httptestclient.Client
+		expected string
+	}{
+		{
+			name: "body",
+			client: func(c *httptestclient.Client) *httptestclient.Client {
+				return c.Post("/api/items").BodyString(`{"id":1,"name":7,"extra":true}`)
+			},
+			expected: "request does not match openapi POST /items\n" +
+				"  request body /extra: is not allowed\n" +
+				"  request body /name: expected string, got number",
+		},
+		{
+			name: "required body",
+			client: func(c *httptestclient.Client) *httptestclient.Client {
+				return c.Post("/api/items")
+			},
+			expected: "request does not match openapi POST /items\n  request body: is required",
+		},
+		{
+			name: "content type",
+			client: func(c *httptestclient.Client) *httptestclient.Client {
+				return c.Post("/api/items").FormData("name", "widget")
+			},
+			expected: "request does not match openapi POST /items\n" +
+				"  request body: content type 'application/x-www-form-urlencoded' is not documented, expected application/json",
+		},
+		{
+			name: "query",
+			client: func(c *httptestclient.Client) *httptestclient.Client {
+				return c.Get("/api/items").Query("limit", "0")
+			},
+			expected: "request does not match openapi GET /items\n  query parameter 'limit': must be >= 1",
+		},
+		{
+			name: "path",
+			client: func(c *httptestclient.Client) *httptestclient.Client {
+				return c.Get("/api/items/$0", "abc")
+			},
+			expected: "request does not match openapi GET /items/{id}\n  path parameter 'id': expected integer, got 'abc'",
+		},
+		{
+			name: "header",
+			client: func(c *httptestclient.Client) *httptestclient.Client {
+				return c.Get("/api/items/$0", 1).Header("X-Request-Id", "42")
+			},
+			expected: "request does not match openapi GET /items/{id}\n  header parameter 'X-Request-Id': is not a valid uuid",
+		},
+		{
+			name: "method",
+			client: func(c *httptestclient.Client) *httptestclient.Client {
+				return c.Put("/api/items/$0", 1)
+			},
+			expected: "openapi has no operation for PUT /api/items/1, allowed DELETE, GET",
+		},
+		{
+			name: "path not documented",
+			client: func(c *httptestclient.Client) *httptestclient.Client {
+				return c.Get("/api/things")
+			},
+			expected: "openapi has no operation for GET /api/things",
+		},
+	}
+	doc := loadItemsOpenAPI(t)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var received int32
+			called := false
+			c := httptestclient.New(self.NewFakeTester(func(format string, args ...interface{}) {
+				called = true
+				assert.Equal(t, tc.expected, fmt.Sprintf(format, args...))
+			})).OpenAPI(doc)
+			tc.client(c).DoSimpleTarget(httptestclient.Handler(openAPIServer(&received)))
+
+			assert.True(t, called)
+			assert.Equal(t, int32(0), received)
+		})
+	}
+}
+
+func Test_openapi_response_violations_fail_the_test(t *testing.T) {
+	testCases := []struct {
+		name     string
+		id       int
+		status   int
+		expected string
+	}{
+		{
+			name:   "body",
+			id:     2,
+			status: http.StatusOK,
+			expected: "response 200 does not match openapi GET /items/{id}\n" +
+				"  response body /colour: is not allowed\n" +
+				"  response body /id: expected integer, got string\n" +
+				"  response body /name: length must be >= 1",
+		},
+		{
+			name:     "status",
+			id:       3,
+			status:   http.StatusInternalServerError,
+			expected: "response 500 does not match openapi GET /items/{id}\n  response status: 500 is not documented",
+		},
+	}
+	session := httptestclient.NewSession(httptestclient.Handler(openAPIServer(new(int32)))).
+		OpenAPI(loadItemsOpenAPI(t))
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			called := false
+			_ = httptestclient.New(self.NewFakeTester(func(format string, args ...interface{}) {
+				called = true
+				assert.Equal(t, tc.expected, fmt.Sprintf(format, args...))
+			})).
+				Get("/api/items/$0", tc.id).
+				ExpectedStatusCode(tc.status).
+				DoSimpleTarget(session)
+
+			assert.True(t, called)
+		})
+	}
+}
+
+func Test_openapi_BuildRequest_is_validated(t *testing.T) {
+	called := false
+	req := httptestclient.New(self.NewFakeTester(func(format string, args ...interface{}) {
+		called = true
+		assert.Equal(t, "request does not match openapi GET /items\n  query parameter 'limit': must be <= 100",
+			fmt.Sprintf(format, args...))
+	})).
+		OpenAPI(loadItemsOpenAPI(t)).
+		Get("/api/items").
+		Query("limit", "500").
+		BuildRequest()
+
+	assert.True(t, called)
+	assert.Nil(t, req)
+}
+
+func Test_LoadOpenAPI_errors(t *testing.T) {
+	dir := t.TempDir()
+	swagger := filepath.Join(dir, "swagger.yaml")
+	require.NoError(t, os.WriteFile(swagger, []byte("swagger: \"2.0\"\npaths: {}\n"), 0o644))
+
+	_, err := httptestclient.LoadOpenAPI(swagger)
+	assert.ErrorContains(t, err, "unsupported openapi version '', expected 3.x")
+
+	_, err = httptestclient.LoadOpenAPI(filepath.Join(dir, "missing.yaml"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,142 @@
+package httptestclient
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Query parameter added to the URL, additive, any query in the URL pattern is kept. Parameters belong to the next
+// request, setting the URL or a parameter after a request has been sent starts a new query
+func (c *Client) Query(key string, values ...string) *Client {
+	if c.query == nil || c.querySent {
+		c.query = url.Values{}
+		c.querySent = false
+	}
+	if len(values) == 0 {
+		// a key with no value, e.g. ?flag=
+		c.query.Add(key, "")
+	}
+	for _, v := range values {
+		c.query.Add(key, v)
+	}
+	return c
+}
+
+// QueryValues adds every key and value, see Query
+func (c *Client) QueryValues(values url.Values) *Client {
+	for k, vs := range values {
+		c.Query(k, vs...)
+	}
+	return c
+}
+
+// QueryStruct adds the exported fields of a struct as query parameters, see Query.
+// Field names can be set with a `url:"name"` tag, `url:"name,omitempty"` skips zero values and `url:"-"` skips the field.
+// Slices add one value per item, a time.Time is formatted as RFC 3339
+func (c *Client) QueryStruct(v any) *Client {
+	if h, ok := c.t.(testingHooks); ok {
+		h.Helper()
+	}
+	values, err := structValues(v)
+	if c.hasError(err) {
+		return c
+	}
+	return c.QueryValues(values)
+}
+
+// withQuery appends the client query to any query already in rawURL
+func (c *Client) withQuery(rawURL string) string {
+	if len(c.query) == 0 {
+		return rawURL
+	}
+	fragment := ""
+	if i := strings.IndexByte(rawURL, '#'); i >= 0 {
+		rawURL, fragment = rawURL[:i], rawURL[i:]
+	}
+	sep := "?"
+	if strings.Contains(rawURL, "?") {
+		sep = "&"
+		if strings.HasSuffix(rawURL, "?") || strings.HasSuffix(rawURL, "&") {
+			sep = ""
+		}
+	}
+	return rawURL + sep + c.query.Encode() + fragment
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// structValues from exported fields using `url` tags
+func structValues(v any) (url.Values, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil, fmt.Errorf("QueryStruct requires a struct, got nil %T", v)
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("QueryStruct requires a struct, got %T", v)
+	}
+	values := url.Values{}
+	addStructValues(values, rv)
+	return values, nil
+}
+
+func addStructValues(values url.Values, rv reflect.Value) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("url")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		fv := rv.Field(i)
+		if f.Anonymous && name == "" && fv.Kind() == reflect.Struct {
+			addStructValues(values, fv)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if opts == "omitempty" && fv.IsZero() {
+			continue
+		}
+		for fv.Kind() == reflect.Pointer {
+			if fv.IsNil() {
+				break
+			}
+			fv = fv.Elem()
+		}
+		if fv.Kind() == reflect.Pointer {
+			continue
+		}
+		if (fv.Kind() == reflect.Slice || fv.Kind() == reflect.Array) && fv.Type().Elem().Kind() != reflect.Uint8 {
+			for j := 0; j < fv.Len(); j++ {
+				values.Add(name, formatValue(fv.Index(j)))
+			}
+			continue
+		}
+		values.Add(name, formatValue(fv))
+	}
+}
+
+func formatValue(v reflect.Value) string {
+	if v.Type() == timeType {
+		return v.Interface().(time.Time).Format(time.RFC3339)
+	}
+	if s, ok := v.Interface().(fmt.Stringer); ok {
+		return s.String()
+	}
+	if v.Kind() == reflect.Slice {
+		// []byte
+		return string(v.Bytes())
+	}
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,114 @@
+package httptestclient_test
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/NearlyUnique/httptestclient"
+	"github.com/NearlyUnique/httptestclient/internal/self"
+	"github.com/stretchr/testify/assert"
+)
+
+type Paging struct {
+	Page int `url:"page"`
+	Size int `url:"size,omitempty"`
+}
+
+type Search struct {
+	Paging
+	Term     string    `url:"q"`
+	Tags     []string  `url:"tag"`
+	Since    time.Time `url:"since,omitempty"`
+	Internal string    `url:"-"`
+	Exact    *bool     `url:"exact"`
+	Untagged string
+	hidden   string
+}
+
+func Test_query_parameters(t *testing.T) {
+	var rawQuery string
+	target := httptestclient.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rawQuery = r.URL.RawQuery
+	}))
+	exact := true
+
+	testData := []struct {
+		name     string
+		client   func(t *testing.T) *httptestclient.Client
+		expected string
+	}{
+		{"single", func(t *testing.T) *httptestclient.Client {
+			return httptestclient.New(t).Query("a", "1")
+		}, "a=1"},
+		{"many values", func(t *testing.T) *httptestclient.Client {
+			return httptestclient.New(t).Query("a", "1", "2").Query("b", "3")
+		}, "a=1&a=2&b=3"},
+		{"no value", func(t *testing.T) *httptestclient.Client {
+			return httptestclient.New(t).Query("flag")
+		}, "flag="},
+		{"escaped", func(t *testing.T) *httptestclient.Client {
+			return httptestclient.New(t).Query("q", "a&b c/d?é")
+		}, "q=a%26b+c%2Fd%3F%C3%A9"},
+		{"merged with url pattern", func(t *testing.T) *httptestclient.Client {
+			return httptestclient.New(t).Get("/any?z=9").Query("a", "1")
+		}, "z=9&a=1"},
+		{"url values", func(t *testing.T) *httptestclient.Client {
+			return httptestclient.New(t).QueryValues(url.Values{"x": {"1"}, "y": {"2", "3"}})
+		}, "x=1&y=2&y=3"},
+		{"struct", func(t *testing.T) *httptestclient.Client {
+			return httptestclient.New(t).QueryStruct(Search{
+				Paging:   Paging{Page: 2},
+				Term:     "go lang",
+				Tags:     []string{"a", "b"},
+				Internal: "never",
+				Exact:    &exact,
+				Untagged: "u",
+				hidden:   "never",
+			})
+		}, "Untagged=u&exact=true&page=2&q=go+lang&tag=a&tag=b"},
+		{"struct pointer", func(t *testing.T) *httptestclient.Client {
+			return httptestclient.New(t).QueryStruct(&Paging{Page: 1, Size: 10})
+		}, "page=1&size=10"},
+		{"time", func(t *testing.T) *httptestclient.Client {
+			return httptestclient.New(t).QueryStruct(Search{
+				Since: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			})
+		}, "Untagged=&page=0&q=&since=2024-01-02T03%3A04%3A05Z"},
+	}
+	for _, td := range testData {
+		t.Run(td.name, func(t *testing.T) {
+			_ = td.client(t).DoSimpleTarget(target)
+
+			assert.Equal(t, td.expected, rawQuery)
+		})
+	}
+	t.Run("the query may be added before the url", func(t *testing.T) {
+		_ = httptestclient.New(t).Query("a", "1").Get("/x").Query("b", "2").DoSimpleTarget(target)
+
+		assert.Equal(t, "a=1&b=2", rawQuery)
+	})
+	t.Run("the query is cleared with the url after sending", func(t *testing.T) {
+		client := httptestclient.New(t).Get("/first").Query("a", "1")
+		_ = client.DoSimpleTarget(target)
+		assert.Equal(t, "a=1", rawQuery)
+
+		_ = client.Get("/second").DoSimpleTarget(target)
+		assert.Equal(t, "", rawQuery)
+
+		_ = client.Query("b", "2").Get("/third").DoSimpleTarget(target)
+		assert.Equal(t, "b=2", rawQuery)
+
+		_ = client.DoSimpleTarget(target)
+		assert.Equal(t, "b=2", rawQuery)
+	})
+	t.Run("only structs are accepted", func(t *testing.T) {
+		_ = httptestclient.New(self.NewFakeTester(func(format string, args ...interface{}) {
+			assert.Equal(t, "Expected no error, got %v", format)
+			assert.EqualError(t, args[0].(error), "QueryStruct requires a struct, got int")
+		})).
+			QueryStruct(1).
+			DoSimpleTarget(target)
+	})
+}
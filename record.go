@@ -0,0 +1,175 @@
+package httptestclient
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// RecordToEnv when set, e.g. HTTPTESTCLIENT_RECORD_TO=traffic.jsonl, every Client records to the file, see Client.RecordTo
+const RecordToEnv = "HTTPTESTCLIENT_RECORD_TO"
+
+// recordMu serialises appends from parallel tests
+var recordMu sync.Mutex
+
+// Recording a request and its response as a single line of a JSON lines file, bodies are base64 encoded.
+// URL is relative to the target, the path and query only
+type Recording struct {
+	Method         string      `json:"method"`
+	URL            string      `json:"url"`
+	Header         http.Header `json:"header,omitempty"`
+	Body           []byte      `json:"body,omitempty"`
+	Status         int         `json:"status"`
+	ResponseHeader http.Header `json:"response_header,omitempty"`
+	ResponseBody   []byte      `json:"response_body,omitempty"`
+}
+
+// ReplayOption configures Replay
+type ReplayOption func(*replayConfig)
+
+type replayConfig struct {
+	headers []string
+	json    []JSONMatchOption
+}
+
+// ReplayHeaders response headers to compare, the default is Content-Type only
+func ReplayHeaders(names ...string) ReplayOption {
+	return func(c *replayConfig) {
+		c.headers = names
+	}
+}
+
+// ReplayJSON options used to compare json response bodies, e.g. ReplayJSON(IgnorePaths("$.id", "$.createdAt"))
+func ReplayJSON(opts ...JSONMatchOption) ReplayOption {
+	return func(c *replayConfig) {
+		c.json = append(c.json, opts...)
+	}
+}
+
+// RecordTo appends each request and response to a JSON lines file for use with Replay, the environment variable
+// HTTPTESTCLIENT_RECORD_TO sets this for every Client
+func (c *Client) RecordTo(path string) *Client {
+	c.recordPath = path
+	return c
+}
+
+// record the exchange when recording is enabled
+func (c *Client) record(e *exchange) error {
+	if c.recordPath == "" {
+		return nil
+	}
+	buf, err := json.Marshal(Recording{
+		Method:         e.request.Method,
+		URL:            e.request.URL.RequestURI(),
+		Header:         e.request.Header,
+		Body:           e.requestBody,
+		Status:         e.response.StatusCode,
+		ResponseHeader: e.response.Header,
+		ResponseBody:   e.responseBody,
+	})
+	if err != nil {
+		return err
+	}
+	recordMu.Lock()
+	defer recordMu.Unlock()
+	f, err := os.OpenFile(c.recordPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(buf, '\n'))
+	return errors.Join(err, f.Close())
+}
+
+// LoadRecordings from a JSON lines file, blank lines are ignored
+func LoadRecordings(path string) ([]Recording, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return readRecordings(f)
+}
+
+func readRecordings(r io.Reader) ([]Recording, error) {
+	var recordings []Recording
+	scanner := bufio.NewScanner(r)
+	// recorded bodies can be large
+	scanner.Buffer(nil, 64<<20)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var rec Recording
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		recordings = append(recordings, rec)
+	}
+	return recordings, scanner.Err()
+}
+
+// Replay sends each recorded request to target, in order, as a subtest and compares the response with the recording.
+// The status must match, json bodies are compared with ExpectJSONMatches and any other body must be equal.
+// Requests share a Session, recorded Cookie headers are not sent as the session manages cookies. Replayed requests
+// are not recorded.
+// `t` use `*testing.T`
+func Replay(t TestingT, target Target, path string, opts ...ReplayOption) {
+	if h, ok := t.(testingHooks); ok {
+		h.Helper()
+	}
+	cfg := replayConfig{headers: []string{"Content-Type"}}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	recordings, err := LoadRecordings(path)
+	if err != nil {
+		t.Errorf("recordings '%s': %v", path, err)
+		t.FailNow()
+		return
+	}
+	session := NewSession(target)
+	for i, rec := range recordings {
+		subtest(t, fmt.Sprintf("%d %s %s", i+1, rec.Method, rec.URL), func(t TestingT) {
+			rec.replay(t, session, cfg)
+		})
+	}
+}
+
+func (rec Recording) replay(t TestingT, session *Session, cfg replayConfig) {
+	if h, ok := t.(testingHooks); ok {
+		h.Helper()
+	}
+	// a replay is not recorded, even when HTTPTESTCLIENT_RECORD_TO is set
+	c := New(t).RecordTo("").ClearHeaders().Method(rec.Method).URL("$0", Raw(rec.URL)).ExpectedStatusCode(rec.Status)
+	if rec.Status >= 300 && rec.Status < 400 {
+		c.NoFollowRedirects()
+	}
+	for k, values := range rec.Header {
+		if http.CanonicalHeaderKey(k) == "Cookie" {
+			continue
+		}
+		c.header[k] = values
+	}
+	if rec.Body != nil {
+		c.BodyBytes(rec.Body)
+	}
+	resp := c.DoSimpleTarget(session)
+	for _, name := range cfg.headers {
+		for _, v := range rec.ResponseHeader.Values(name) {
+			resp.ExpectHeader(name, v)
+		}
+	}
+	if json.Valid(rec.ResponseBody) && json.Valid([]byte(resp.Body)) {
+		resp.ExpectJSONMatches(rec.ResponseBody, cfg.json...)
+		return
+	}
+	resp.ExpectBodyEquals(string(rec.ResponseBody))
+}
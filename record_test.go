@@ -0,0 +1,89 @@
+package httptestclient_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/NearlyUnique/httptestclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_record_and_replay(t *testing.T) {
+	var calls atomic.Int32
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		switch r.URL.Path {
+		case "/echo":
+			buf, _ := io.ReadAll(r.Body)
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = fmt.Fprintf(w, `{"call":%d,"echo":%s}`, n, buf)
+		case "/text":
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte("hello"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	path := filepath.Join(t.TempDir(), "recording.jsonl")
+	target := httptestclient.Handler(handler)
+
+	_ = httptestclient.New(t).RecordTo(path).Post("/echo?a=1").BodyString(`{"name":"widget"}`).DoSimpleTarget(target)
+	_ = httptestclient.New(t).RecordTo(path).Get("/text").DoSimpleTarget(target)
+	_ = httptestclient.New(t).RecordTo(path).Get("/missing").ExpectedStatusCode(http.StatusNotFound).DoSimpleTarget(target)
+
+	t.Run("each exchange is a line", func(t *testing.T) {
+		recordings, err := httptestclient.LoadRecordings(path)
+
+		require.NoError(t, err)
+		require.Len(t, recordings, 3)
+		assert.Equal(t, "POST", recordings[0].Method)
+		assert.Equal(t, "/echo?a=1", recordings[0].URL)
+		assert.Equal(t, "application/json", recordings[0].Header.Get("Content-Type"))
+		assert.Equal(t, `{"name":"widget"}`, string(recordings[0].Body))
+		assert.Equal(t, http.StatusOK, recordings[0].Status)
+		assert.Equal(t, `{"call":1,"echo":{"name":"widget"}}`, string(recordings[0].ResponseBody))
+		assert.Equal(t, http.StatusNotFound, recordings[2].Status)
+	})
+	t.Run("replay ignoring volatile values", func(t *testing.T) {
+		httptestclient.Replay(t, target, path, httptestclient.ReplayJSON(httptestclient.IgnorePaths("$.call")))
+
+		assert.Equal(t, int32(6), calls.Load())
+	})
+}
+
+func Test_load_recordings_reports_the_bad_line(t *testing.T) {
+	_, err := httptestclient.LoadRecordings(filepath.Join("testdata", "recordings", "bad.jsonl"))
+
+	assert.EqualError(t, err, "line 3: invalid character 'n' looking for beginning of object key string")
+}
+
+func Test_every_client_records_when_the_environment_variable_is_set(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recording.jsonl")
+	t.Setenv(httptestclient.RecordToEnv, path)
+	target := httptestclient.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	_ = httptestclient.New(t).Get("/first").DoSimpleTarget(target)
+	_ = httptestclient.New(t).Get("/second").DoSimpleTarget(target)
+
+	recordings, err := httptestclient.LoadRecordings(path)
+	require.NoError(t, err)
+	require.Len(t, recordings, 2)
+	assert.Equal(t, "/second", recordings[1].URL)
+}
+
+func Test_replay_is_not_recorded(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "recording.jsonl")
+	target := httptestclient.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	_ = httptestclient.New(t).RecordTo(path).Get("/items").DoSimpleTarget(target)
+	t.Setenv(httptestclient.RecordToEnv, filepath.Join(dir, "replay.jsonl"))
+
+	httptestclient.Replay(t, target, path)
+
+	assert.NoFileExists(t, filepath.Join(dir, "replay.jsonl"))
+}
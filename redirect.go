@@ -0,0 +1,57 @@
+package httptestclient
+
+import (
+	"net/http"
+)
+
+// RedirectHop a single redirect followed while executing a request
+type RedirectHop struct {
+	// Status of the redirect response, e.g. 302
+	Status int
+	// Location header of the redirect response, as sent by the server
+	Location string
+	// Method used to follow the redirect, 303 always changes to GET
+	Method string
+	// Host redirected to
+	Host string
+	// Path redirected to
+	Path string
+}
+
+// newRedirectHop from the request about to be made to follow a redirect
+func newRedirectHop(req *http.Request) RedirectHop {
+	hop := RedirectHop{
+		Method: req.Method,
+		Host:   req.URL.Host,
+		Path:   req.URL.Path,
+	}
+	if req.Response != nil {
+		hop.Status = req.Response.StatusCode
+		hop.Location = req.Response.Header.Get("Location")
+	}
+	return hop
+}
+
+// redirectPaths of each hop
+func redirectPaths(hops []RedirectHop) []string {
+	paths := make([]string, 0, len(hops))
+	for _, h := range hops {
+		paths = append(paths, h.Path)
+	}
+	return paths
+}
+
+// checkRedirectLocation of an unfollowed redirect, returns true if the response was a redirect
+func (c *Client) checkRedirectLocation(resp *http.Response, expectedPath string) bool {
+	if h, ok := c.t.(testingHooks); ok {
+		h.Helper()
+	}
+	loc, err := resp.Location()
+	if err != nil {
+		return false
+	}
+	if loc.Path != expectedPath {
+		c.failNow("expected to redirect path '%s', actual path '%s'", expectedPath, loc.Path)
+	}
+	return true
+}
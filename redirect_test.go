@@ -0,0 +1,110 @@
+package httptestclient_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/NearlyUnique/httptestclient"
+	"github.com/NearlyUnique/httptestclient/internal/self"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func redirectChainHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/start":
+			http.Redirect(w, r, "/middle", http.StatusFound)
+		case "/middle":
+			http.Redirect(w, r, "/end", http.StatusSeeOther)
+		case "/end":
+			_, _ = fmt.Fprint(w, r.Method)
+		}
+	})
+}
+
+func Test_redirect_chains(t *testing.T) {
+	s := httptest.NewServer(redirectChainHandler())
+	defer s.Close()
+
+	t.Run("every hop is available on the response", func(t *testing.T) {
+		resp := httptestclient.New(t).
+			Post("/start").
+			ExpectRedirectChain("/middle", "/end").
+			DoSimple(s)
+
+		host := s.Listener.Addr().String()
+		assert.Equal(t, []httptestclient.RedirectHop{
+			{Status: http.StatusFound, Location: "/middle", Method: http.MethodGet, Host: host, Path: "/middle"},
+			{Status: http.StatusSeeOther, Location: "/end", Method: http.MethodGet, Host: host, Path: "/end"},
+		}, resp.Redirects)
+		assert.Equal(t, "/middle,/end", resp.RedirectedVia)
+		assert.Equal(t, http.MethodGet, resp.Body)
+	})
+	t.Run("a different chain fails the test", func(t *testing.T) {
+		_ = httptestclient.New(self.NewFakeTester(func(format string, args ...interface{}) {
+			assert.Equal(t, "expected redirect chain %v, actual %v", format)
+			require.Equal(t, 2, len(args))
+			assert.Equal(t, []string{"/end"}, args[0])
+			assert.Equal(t, []string{"/middle", "/end"}, args[1])
+		})).
+			Get("/start").
+			ExpectRedirectChain("/end").
+			DoSimple(s)
+	})
+	t.Run("an empty chain expects no redirects", func(t *testing.T) {
+		_ = httptestclient.New(self.NewFakeTester(func(format string, args ...interface{}) {
+			assert.Equal(t, "expected redirect chain %v, actual %v", format)
+		})).
+			Get("/start").
+			ExpectRedirectChain().
+			DoSimple(s)
+	})
+}
+
+func Test_redirects_need_not_be_followed(t *testing.T) {
+	s := httptest.NewServer(redirectChainHandler())
+	defer s.Close()
+
+	t.Run("the redirect is the final response", func(t *testing.T) {
+		resp := httptestclient.New(t).
+			Get("/start").
+			NoFollowRedirects().
+			ExpectedStatusCode(http.StatusFound).
+			DoSimple(s)
+
+		assert.Equal(t, http.StatusFound, resp.Status)
+		assert.Equal(t, "/middle", resp.Header.Get("Location"))
+		assert.Empty(t, resp.Redirects)
+	})
+	t.Run("the order of NoFollowRedirects does not matter", func(t *testing.T) {
+		resp := httptestclient.New(t).
+			Get("/middle").
+			ExpectedStatusCode(http.StatusSeeOther).
+			NoFollowRedirects().
+			DoSimple(s)
+
+		assert.Equal(t, http.StatusSeeOther, resp.Status)
+	})
+	t.Run("the redirect location can be expected", func(t *testing.T) {
+		_ = httptestclient.New(t).
+			Get("/start").
+			NoFollowRedirects().
+			ExpectRedirectTo("/middle").
+			DoSimple(s)
+	})
+	t.Run("the wrong redirect location fails", func(t *testing.T) {
+		_ = httptestclient.New(self.NewFakeTester(func(format string, args ...interface{}) {
+			assert.Equal(t, "expected to redirect path '%s', actual path '%s'", format)
+			require.Equal(t, 2, len(args))
+			assert.Equal(t, "/end", args[0])
+			assert.Equal(t, "/middle", args[1])
+		})).
+			Get("/start").
+			NoFollowRedirects().
+			ExpectRedirectTo("/end").
+			DoSimple(s)
+	})
+}
@@ -0,0 +1,246 @@
+package httptestclient
+
+import (
+	"encoding/json"
+	"mime"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/NearlyUnique/httptestclient/internal/jsonschema"
+)
+
+// ExpectHeader to have value, any value of a multi-value header may match
+func (r SimpleResponse) ExpectHeader(name, value string) SimpleResponse {
+	if r.t == nil {
+		return r
+	}
+	if h, ok := r.t.(testingHooks); ok {
+		h.Helper()
+	}
+	actual := r.Header.Values(name)
+	if !slices.Contains(actual, value) {
+		r.t.Errorf("expected header '%s' to be '%s', got %q", name, value, actual)
+	}
+	return r
+}
+
+// ExpectHeaderMatches the regular expression pattern, any value of a multi-value header may match
+func (r SimpleResponse) ExpectHeaderMatches(name, pattern string) SimpleResponse {
+	if r.t == nil {
+		return r
+	}
+	if h, ok := r.t.(testingHooks); ok {
+		h.Helper()
+	}
+	rx, err := regexp.Compile(pattern)
+	if err != nil {
+		r.t.Errorf("invalid header pattern '%s': %v", pattern, err)
+		return r
+	}
+	actual := r.Header.Values(name)
+	if !slices.ContainsFunc(actual, rx.MatchString) {
+		r.t.Errorf("expected header '%s' to match '%s', got %q", name, pattern, actual)
+	}
+	return r
+}
+
+// ExpectBodyEquals the exact string
+func (r SimpleResponse) ExpectBodyEquals(expected string) SimpleResponse {
+	if r.t == nil {
+		return r
+	}
+	if h, ok := r.t.(testingHooks); ok {
+		h.Helper()
+	}
+	if r.Body != expected {
+		r.t.Errorf("expected body %q, got %q", expected, r.Body)
+	}
+	return r
+}
+
+// ExpectBodyContains the substring
+func (r SimpleResponse) ExpectBodyContains(substr string) SimpleResponse {
+	if r.t == nil {
+		return r
+	}
+	if h, ok := r.t.(testingHooks); ok {
+		h.Helper()
+	}
+	if !strings.Contains(r.Body, substr) {
+		r.t.Errorf("expected body to contain %q, got %q", substr, r.Body)
+	}
+	return r
+}
+
+// ExpectJSONEq the body is semantically equal to the expected json, whitespace and key order are ignored and
+// numbers are compared by value, every digit of a large integer
+func (r SimpleResponse) ExpectJSONEq(expected string) SimpleResponse {
+	if r.t == nil {
+		return r
+	}
+	if h, ok := r.t.(testingHooks); ok {
+		h.Helper()
+	}
+	want, err := decodeJSON([]byte(expected), true)
+	if err != nil {
+		r.t.Errorf("expected value is not valid json: %v", err)
+		return r
+	}
+	got, err := decodeJSON([]byte(r.Body), true)
+	if err != nil {
+		r.t.Errorf("body is not valid json: %v", err)
+		return r
+	}
+	if !equalJSON(want, got) {
+		r.t.Errorf("expected json %s, got %s", expected, r.Body)
+	}
+	return r
+}
+
+// ExpectJSONSchema the body is valid against schema, json text as a string or []byte, or any value that marshals
+// to a schema. Draft 2020-12 keywords for types, properties, items, enum, const, pattern, format, min/max,
+// allOf/anyOf/oneOf/not and if/then/else are supported, $ref must be local to the schema. Every violation is
+// reported with the JSON pointer of the offending value
+func (r SimpleResponse) ExpectJSONSchema(schema any) SimpleResponse {
+	if r.t == nil {
+		return r
+	}
+	if h, ok := r.t.(testingHooks); ok {
+		h.Helper()
+	}
+	s, err := parseExpectedJSON(schema)
+	if err != nil {
+		r.t.Errorf("schema is not valid json: %v", err)
+		return r
+	}
+	got, err := decodeJSON([]byte(r.Body), true)
+	if err != nil {
+		r.t.Errorf("body is not valid json: %v", err)
+		return r
+	}
+	if violations := jsonschema.Validate(s, got); len(violations) > 0 {
+		lines := make([]string, len(violations))
+		for i, v := range violations {
+			lines[i] = "  " + v.String()
+		}
+		r.t.Errorf("json does not match schema:\n%s", strings.Join(lines, "\n"))
+	}
+	return r
+}
+
+// ExpectContentType media type, parameters such as charset are ignored
+func (r SimpleResponse) ExpectContentType(mediaType string) SimpleResponse {
+	if r.t == nil {
+		return r
+	}
+	if h, ok := r.t.(testingHooks); ok {
+		h.Helper()
+	}
+	actual := r.Header.Get("Content-Type")
+	mt, _, err := mime.ParseMediaType(actual)
+	if err != nil || !strings.EqualFold(mt, mediaType) {
+		r.t.Errorf("expected content type '%s', got '%s'", mediaType, actual)
+	}
+	return r
+}
+
+// JSONPath value selected from the body, e.g. "$.items[0].id", numbers are float64 as per encoding/json, use
+// JSONPathAs for integers beyond 2^53.
+// Paths that can select many values, wildcards, filters, slices or recursive descent, return []any
+func (r SimpleResponse) JSONPath(path string) any {
+	if r.t == nil {
+		return nil
+	}
+	if h, ok := r.t.(testingHooks); ok {
+		h.Helper()
+	}
+	v, _ := r.jsonPath(path)
+	return v
+}
+
+// JSONPathAs selects the value at path as type T, see SimpleResponse.JSONPath
+func JSONPathAs[T any](r SimpleResponse, path string) T {
+	var out T
+	if r.t == nil {
+		return out
+	}
+	if h, ok := r.t.(testingHooks); ok {
+		h.Helper()
+	}
+	v, ok := r.selectJSONPath(path, true)
+	if !ok {
+		return out
+	}
+	buf, err := json.Marshal(v)
+	if err == nil {
+		err = json.Unmarshal(buf, &out)
+	}
+	if err != nil {
+		r.t.Errorf("json path '%s' cannot be converted to %T: %v", path, out, err)
+	}
+	return out
+}
+
+// ExpectJSONPath value to equal expected, expected is compared as json so 1 and 1.0 are equal, large integers are
+// compared exactly
+func (r SimpleResponse) ExpectJSONPath(path string, expected any) SimpleResponse {
+	if r.t == nil {
+		return r
+	}
+	if h, ok := r.t.(testingHooks); ok {
+		h.Helper()
+	}
+	actual, ok := r.selectJSONPath(path, true)
+	if !ok {
+		return r
+	}
+	want, err := normalizeJSON(expected)
+	if err != nil {
+		r.t.Errorf("expected value cannot be converted to json: %v", err)
+		return r
+	}
+	if !equalJSON(want, actual) {
+		w, _ := json.Marshal(want)
+		a, _ := json.Marshal(actual)
+		r.t.Errorf("expected json path '%s' to be %s, got %s", path, w, a)
+	}
+	return r
+}
+
+// jsonPath reports any failure to the test, ok is false when nothing could be selected
+func (r SimpleResponse) jsonPath(path string) (any, bool) {
+	if h, ok := r.t.(testingHooks); ok {
+		h.Helper()
+	}
+	return r.selectJSONPath(path, false)
+}
+
+// selectJSONPath as jsonPath, with useNumber numbers are selected as json.Number
+func (r SimpleResponse) selectJSONPath(path string, useNumber bool) (any, bool) {
+	if h, ok := r.t.(testingHooks); ok {
+		h.Helper()
+	}
+	jp, err := compileJSONPath(path)
+	if err != nil {
+		r.t.Errorf("invalid json path '%s': %v", path, err)
+		return nil, false
+	}
+	doc, err := decodeJSON([]byte(r.Body), useNumber)
+	if err != nil {
+		r.t.Errorf("body is not valid json: %v", err)
+		return nil, false
+	}
+	values := jp.evaluate(doc)
+	if !jp.definite() {
+		if values == nil {
+			values = []any{}
+		}
+		return values, true
+	}
+	if len(values) == 0 {
+		r.t.Errorf("json path '%s' not found in %s", path, r.Body)
+		return nil, false
+	}
+	return values[0], true
+}
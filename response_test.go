@@ -0,0 +1,209 @@
+package httptestclient
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/NearlyUnique/httptestclient/internal/self"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestResponse(errs *[]string, body string, header http.Header) SimpleResponse {
+	return SimpleResponse{
+		Header: header,
+		Body:   body,
+		Status: http.StatusOK,
+		t: self.NewFakeTester(func(format string, args ...interface{}) {
+			*errs = append(*errs, fmt.Sprintf(format, args...))
+		}),
+	}
+}
+
+func Test_response_assertions_pass(t *testing.T) {
+	var errs []string
+	header := http.Header{}
+	header.Set("Content-Type", "application/json; charset=utf-8")
+	header.Add("X-Many", "one")
+	header.Add("X-Many", "two")
+
+	newTestResponse(&errs, `{"a":1, "b":[true,null]}`, header).
+		ExpectHeader("x-many", "two").
+		ExpectHeaderMatches("Content-Type", `^application/json`).
+		ExpectBodyContains(`"a":1`).
+		ExpectBodyEquals(`{"a":1, "b":[true,null]}`).
+		ExpectJSONEq(`{"b":[true, null],"a":1.0}`).
+		ExpectContentType("application/json")
+
+	assert.Empty(t, errs)
+}
+
+func Test_response_assertions_fail(t *testing.T) {
+	header := http.Header{}
+	header.Set("Content-Type", "text/plain")
+
+	testData := []struct {
+		name     string
+		assert   func(SimpleResponse)
+		expected string
+	}{
+		{"header", func(r SimpleResponse) { r.ExpectHeader("content-type", "text/html") },
+			`expected header 'content-type' to be 'text/html', got ["text/plain"]`},
+		{"missing header", func(r SimpleResponse) { r.ExpectHeader("missing", "any") },
+			`expected header 'missing' to be 'any', got []`},
+		{"header pattern", func(r SimpleResponse) { r.ExpectHeaderMatches("content-type", "json$") },
+			`expected header 'content-type' to match 'json$', got ["text/plain"]`},
+		{"bad header pattern", func(r SimpleResponse) { r.ExpectHeaderMatches("content-type", "(") },
+			"invalid header pattern '(': error parsing regexp: missing closing ): `(`"},
+		{"body", func(r SimpleResponse) { r.ExpectBodyEquals("other") },
+			`expected body "other", got "{\"a\":1}"`},
+		{"body contains", func(r SimpleResponse) { r.ExpectBodyContains("other") },
+			`expected body to contain "other", got "{\"a\":1}"`},
+		{"json", func(r SimpleResponse) { r.ExpectJSONEq(`{"a":2}`) },
+			`expected json {"a":2}, got {"a":1}`},
+		{"invalid expected json", func(r SimpleResponse) { r.ExpectJSONEq(`{`) },
+			`expected value is not valid json: unexpected end of JSON input`},
+		{"content type", func(r SimpleResponse) { r.ExpectContentType("application/json") },
+			`expected content type 'application/json', got 'text/plain'`},
+	}
+	for _, td := range testData {
+		t.Run(td.name, func(t *testing.T) {
+			var errs []string
+			td.assert(newTestResponse(&errs, `{"a":1}`, header))
+
+			assert.Equal(t, []string{td.expected}, errs)
+		})
+	}
+}
+
+func Test_json_schema_lists_every_violation(t *testing.T) {
+	schema := `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type": "object",
+		"required": ["id", "items"],
+		"properties": {
+			"id": {"type": "string", "pattern": "^ord-[0-9]+$"},
+			"status": {"enum": ["open", "closed"]},
+			"items": {"type": "array", "minItems": 1, "items": {"$ref": "#/$defs/item"}}
+		},
+		"$defs": {
+			"item": {
+				"type": "object",
+				"required": ["sku", "quantity"],
+				"properties": {
+					"sku": {"type": "string"},
+					"quantity": {"type": "integer", "minimum": 1, "maximum": 10}
+				}
+			}
+		}
+	}`
+
+	var errs []string
+	newTestResponse(&errs, `{"id":"ord-1","status":"open","items":[{"sku":"a","quantity":2}]}`, http.Header{}).
+		ExpectJSONSchema(schema).
+		ExpectJSONSchema(map[string]any{"type": "object"}).
+		ExpectJSONSchema([]byte(`true`))
+	assert.Empty(t, errs)
+
+	newTestResponse(&errs, `{"id":"1","status":"lost","items":[{"sku":"a","quantity":0},{"quantity":1.5}]}`, http.Header{}).
+		ExpectJSONSchema(schema)
+	assert.Equal(t, []string{"json does not match schema:\n" +
+		"  /id: does not match pattern '^ord-[0-9]+$'\n" +
+		"  /items/0/quantity: must be >= 1\n" +
+		"  /items/1/sku: is required\n" +
+		"  /items/1/quantity: expected integer, got number\n" +
+		`  /status: must be one of ["open","closed"]`}, errs)
+}
+
+func Test_json_schema_failures(t *testing.T) {
+	testData := []struct {
+		name     string
+		body     string
+		schema   any
+		expected string
+	}{
+		{"invalid schema", `{}`, `{`, "schema is not valid json: unexpected end of JSON input"},
+		{"not json", `plain`, `{}`, "body is not valid json: invalid character 'p' looking for beginning of value"},
+		{"root", `[]`, `{"type":"object"}`, "json does not match schema:\n  (root): expected object, got array"},
+	}
+	for _, td := range testData {
+		t.Run(td.name, func(t *testing.T) {
+			var errs []string
+			newTestResponse(&errs, td.body, http.Header{}).ExpectJSONSchema(td.schema)
+
+			assert.Equal(t, []string{td.expected}, errs)
+		})
+	}
+}
+
+func Test_response_assertions_are_ignored_when_the_request_already_failed(t *testing.T) {
+	assert.NotPanics(t, func() {
+		SimpleResponse{}.ExpectBodyEquals("any").ExpectHeader("any", "any")
+	})
+}
+
+func Test_json_path_values_can_be_read_from_the_body(t *testing.T) {
+	var errs []string
+	r := newTestResponse(&errs, `{"value":"any","items":[{"id":1},{"id":2}]}`, http.Header{})
+
+	assert.Equal(t, "any", r.JSONPath("$.value"))
+	assert.Equal(t, float64(2), r.JSONPath("$.items[1].id"))
+	assert.Equal(t, []any{float64(1), float64(2)}, r.JSONPath("$.items[*].id"))
+	assert.Equal(t, []any{}, r.JSONPath("$.items[?(@.id > 5)]"))
+	assert.Equal(t, 2, JSONPathAs[int](r, "$.items[1].id"))
+	assert.Equal(t, []int{1, 2}, JSONPathAs[[]int](r, "$..id"))
+	assert.Empty(t, errs)
+
+	r.ExpectJSONPath("$.value", "any").
+		ExpectJSONPath("$.items[0]", map[string]any{"id": 1}).
+		ExpectJSONPath("$.items[*].id", []int{1, 2})
+	assert.Empty(t, errs)
+}
+
+func Test_large_integers_are_compared_exactly(t *testing.T) {
+	var errs []string
+	r := newTestResponse(&errs, `{"id":9007199254740993,"n":1.0}`, http.Header{})
+
+	r.ExpectJSONEq(`{"id":9007199254740993,"n":1}`).
+		ExpectJSONPath("$.id", int64(9007199254740993)).
+		ExpectJSONSchema(`{"properties":{"id":{"const":9007199254740993}}}`)
+	assert.Equal(t, int64(9007199254740993), JSONPathAs[int64](r, "$.id"))
+	assert.Empty(t, errs)
+
+	r.ExpectJSONEq(`{"id":9007199254740992,"n":1}`).
+		ExpectJSONPath("$.id", int64(9007199254740992)).
+		ExpectJSONSchema(`{"properties":{"id":{"const":9007199254740992}}}`)
+	assert.Equal(t, []string{
+		`expected json {"id":9007199254740992,"n":1}, got {"id":9007199254740993,"n":1.0}`,
+		`expected json path '$.id' to be 9007199254740992, got 9007199254740993`,
+		"json does not match schema:\n  /id: must be 9007199254740992",
+	}, errs)
+}
+
+func Test_json_path_failures(t *testing.T) {
+	testData := []struct {
+		name     string
+		body     string
+		assert   func(SimpleResponse)
+		expected string
+	}{
+		{"value differs", `{"a":1}`, func(r SimpleResponse) { r.ExpectJSONPath("$.a", 2) },
+			`expected json path '$.a' to be 2, got 1`},
+		{"missing", `{"a":1}`, func(r SimpleResponse) { r.ExpectJSONPath("$.b", 1) },
+			`json path '$.b' not found in {"a":1}`},
+		{"invalid path", `{"a":1}`, func(r SimpleResponse) { r.JSONPath("a") },
+			`invalid json path 'a': must start with '$'`},
+		{"not json", `plain`, func(r SimpleResponse) { r.JSONPath("$.a") },
+			`body is not valid json: invalid character 'p' looking for beginning of value`},
+		{"wrong type", `{"a":"text"}`, func(r SimpleResponse) { JSONPathAs[int](r, "$.a") },
+			`json path '$.a' cannot be converted to int: json: cannot unmarshal string into Go value of type int`},
+	}
+	for _, td := range testData {
+		t.Run(td.name, func(t *testing.T) {
+			var errs []string
+			td.assert(newTestResponse(&errs, td.body, http.Header{}))
+
+			assert.Equal(t, []string{td.expected}, errs)
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package httptestclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// Scenario runs named steps in order against a single Session, each step is a subtest. Values captured from
+// a response are available to later steps as ${name} in the URL, headers and BodyString, see Client.Var.
+// Once a step fails the remaining steps are skipped and the transcript of the earlier steps is logged
+//
+//	s := httptestclient.NewScenario(t, httptestclient.Server(server))
+//	s.Step("create", func(c *httptestclient.Client) *httptestclient.Client {
+//		return c.Post("/items").BodyString(`{"name":"widget"}`)
+//	}, httptestclient.CaptureJSONPath("id", "$.id"))
+//	s.Step("read", func(c *httptestclient.Client) *httptestclient.Client {
+//		return c.Get("/items/${id}")
+//	})
+type Scenario struct {
+	t       TestingT
+	session *Session
+	vars    map[string]any
+	done    []scenarioStep
+	failed  string
+}
+
+// scenarioStep a completed step
+type scenarioStep struct {
+	name       string
+	transcript string
+}
+
+// Capture sets scenario variables from the response of a step
+type Capture func(r SimpleResponse, vars map[string]any) error
+
+// subtests testing clients do not need to expose this, when available each step is a subtest
+type subtests interface {
+	Run(name string, f func(t *testing.T)) bool
+}
+
+// skipper testing clients do not need to expose this, when available steps after a failure are reported as skipped
+type skipper interface {
+	Skipf(format string, args ...interface{})
+}
+
+// NewScenario for target, the steps share cookies as they use the same Session.
+// `t` use `*testing.T`, without Run the steps are not subtests
+func NewScenario(t TestingT, target Target) *Scenario {
+	session, ok := target.(*Session)
+	if !ok {
+		session = NewSession(target)
+	}
+	return &Scenario{t: t, session: session, vars: map[string]any{}}
+}
+
+// Var sets a variable for all later steps
+func (s *Scenario) Var(name string, value any) *Scenario {
+	s.vars[name] = value
+	return s
+}
+
+// Value of a variable, nil if it has not been set
+func (s *Scenario) Value(name string) any {
+	return s.vars[name]
+}
+
+// Step builds a request from a new Client, with the scenario variables, and sends it. The response is returned
+// for further assertions, it is empty if the step failed or was skipped
+func (s *Scenario) Step(name string, build func(c *Client) *Client, captures ...Capture) SimpleResponse {
+	if h, ok := s.t.(testingHooks); ok {
+		h.Helper()
+	}
+	var resp SimpleResponse
+	subtest(s.t, name, func(t TestingT) {
+		if resp = s.step(t, name, build, captures); resp.t != nil {
+			resp.t = s.t
+		}
+	})
+	return resp
+}
+
+// subtest runs f as a subtest of t when t has Run, otherwise f is called with t
+func subtest(t TestingT, name string, f func(t TestingT)) {
+	if r, ok := t.(subtests); ok {
+		r.Run(name, func(t *testing.T) {
+			t.Helper()
+			f(t)
+		})
+		return
+	}
+	f(t)
+}
+
+func (s *Scenario) step(t TestingT, name string, build func(c *Client) *Client, captures []Capture) SimpleResponse {
+	h, hooked := t.(testingHooks)
+	if hooked {
+		h.Helper()
+	}
+	if s.failed != "" {
+		if sk, ok := t.(skipper); ok {
+			sk.Skipf("skipped, step '%s' failed", s.failed)
+		}
+		return SimpleResponse{}
+	}
+	earlier := s.transcript()
+	failed := func() {
+		if s.failed != "" {
+			return
+		}
+		s.failed = name
+		if l, ok := t.(logger); ok && earlier != "" {
+			l.Logf("earlier steps\n%s", earlier)
+		}
+	}
+	if hooked {
+		h.Cleanup(func() {
+			if h.Failed() {
+				failed()
+			}
+		})
+	}
+	c := New(t).Vars(s.vars)
+	r := build(c).DoSimpleTarget(s.session)
+	if c.err != nil {
+		failed()
+		return SimpleResponse{}
+	}
+	s.done = append(s.done, scenarioStep{name: name, transcript: c.Transcript()})
+	for _, capture := range captures {
+		if err := capture(r, s.vars); err != nil {
+			failed()
+			t.Errorf("capture failed: %v", err)
+			t.FailNow()
+			return SimpleResponse{}
+		}
+	}
+	return r
+}
+
+// transcript of the completed steps
+func (s *Scenario) transcript() string {
+	var sb strings.Builder
+	for _, step := range s.done {
+		_, _ = fmt.Fprintf(&sb, "=== step '%s' ===\n%s", step.name, step.transcript)
+	}
+	return sb.String()
+}
+
+// CaptureJSONPath sets the variable name from the json path, which must select exactly one value, e.g. "$.id" or
+// "$.items[?(@.sku == 'a')].id"
+func CaptureJSONPath(name, path string) Capture {
+	return func(r SimpleResponse, vars map[string]any) error {
+		jp, err := compileJSONPath(path)
+		if err != nil {
+			return fmt.Errorf("invalid json path '%s': %w", path, err)
+		}
+		doc, err := decodeJSON([]byte(r.Body), true)
+		if err != nil {
+			return fmt.Errorf("body is not valid json: %w", err)
+		}
+		switch values := jp.evaluate(doc); len(values) {
+		case 0:
+			return fmt.Errorf("json path '%s' not found in %s", path, r.Body)
+		case 1:
+			vars[name] = varValue(values[0])
+			return nil
+		default:
+			return fmt.Errorf("json path '%s' selected %d values, a capture needs exactly one", path, len(values))
+		}
+	}
+}
+
+// CaptureHeader sets the variable name from the first value of a response header
+func CaptureHeader(name, header string) Capture {
+	return func(r SimpleResponse, vars map[string]any) error {
+		values := r.Header.Values(header)
+		if len(values) == 0 {
+			return fmt.Errorf("header '%s' not found", header)
+		}
+		vars[name] = values[0]
+		return nil
+	}
+}
+
+// CaptureCookie sets the variable name from a cookie set by the response
+func CaptureCookie(name, cookie string) Capture {
+	return func(r SimpleResponse, vars map[string]any) error {
+		if r.Response != nil {
+			for _, c := range r.Response.Cookies() {
+				if c.Name == cookie {
+					vars[name] = c.Value
+					return nil
+				}
+			}
+		}
+		return fmt.Errorf("cookie '%s' not found", cookie)
+	}
+}
+
+// varValue json numbers, decoded as json.Number, are kept as written so large ids can be used in a URL
+func varValue(v any) any {
+	if n, ok := v.(json.Number); ok {
+		return n.String()
+	}
+	return v
+}
@@ -0,0 +1,138 @@
+package httptestclient_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/NearlyUnique/httptestclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// itemServer a minimal CRUD api, creating an item also sets a session cookie
+func itemServer() http.Handler {
+	var mu sync.Mutex
+	items := map[string]string{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /items", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		buf, _ := io.ReadAll(r.Body)
+		items["1001"] = string(buf)
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "s-1", Path: "/"})
+		w.Header().Set("Location", "/items/1001")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id":1001}`))
+	})
+	mux.HandleFunc("GET /items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if c, err := r.Cookie("session"); err != nil || c.Value != "s-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		item, ok := items[r.PathValue("id")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(item))
+	})
+	mux.HandleFunc("DELETE /items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		delete(items, r.PathValue("id"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return mux
+}
+
+func Test_scenario_steps_share_captured_values(t *testing.T) {
+	server := httptest.NewServer(itemServer())
+	defer server.Close()
+
+	s := httptestclient.NewScenario(t, httptestclient.Server(server)).
+		Var("name", "widget")
+
+	s.Step("create", func(c *httptestclient.Client) *httptestclient.Client {
+		return c.Post("/items").BodyString(`{"name":"${name}"}`).ExpectedStatusCode(http.StatusCreated)
+	},
+		httptestclient.CaptureJSONPath("id", "$.id"),
+		httptestclient.CaptureHeader("location", "Location"),
+		httptestclient.CaptureCookie("session", "session"),
+	)
+	s.Step("read", func(c *httptestclient.Client) *httptestclient.Client {
+		return c.Get("/items/${id}")
+	}).ExpectJSONPath("$.name", "widget")
+	s.Step("delete", func(c *httptestclient.Client) *httptestclient.Client {
+		return c.Delete("/items/${id}").ExpectedStatusCode(http.StatusNoContent)
+	})
+	resp := s.Step("read deleted", func(c *httptestclient.Client) *httptestclient.Client {
+		return c.Get("/items/${id}").ExpectedStatusCode(http.StatusNotFound)
+	})
+
+	assert.Equal(t, http.StatusNotFound, resp.Status)
+	assert.Equal(t, "1001", s.Value("id"))
+	assert.Equal(t, "/items/1001", s.Value("location"))
+	assert.Equal(t, "s-1", s.Value("session"))
+}
+
+func Test_scenario_captures(t *testing.T) {
+	resp := httptestclient.SimpleResponse{
+		Header: http.Header{"X-Request-Id": {"r-1"}},
+		Body:   `{"items":[{"id":12345678,"big":9007199254740993}]}`,
+	}
+
+	t.Run("values are set", func(t *testing.T) {
+		vars := map[string]any{}
+
+		require.NoError(t, httptestclient.CaptureJSONPath("id", "$.items[0].id")(resp, vars))
+		require.NoError(t, httptestclient.CaptureJSONPath("big", "$.items[0].big")(resp, vars))
+		require.NoError(t, httptestclient.CaptureHeader("request", "X-Request-Id")(resp, vars))
+
+		assert.Equal(t, map[string]any{"id": "12345678", "big": "9007199254740993", "request": "r-1"}, vars)
+	})
+	t.Run("missing values are errors", func(t *testing.T) {
+		vars := map[string]any{}
+
+		assert.EqualError(t, httptestclient.CaptureJSONPath("id", "$.missing")(resp, vars),
+			`json path '$.missing' not found in {"items":[{"id":12345678,"big":9007199254740993}]}`)
+		assert.EqualError(t, httptestclient.CaptureJSONPath("id", "$.items[0].*")(resp, vars),
+			"json path '$.items[0].*' selected 2 values, a capture needs exactly one")
+		assert.EqualError(t, httptestclient.CaptureHeader("h", "X-Missing")(resp, vars), "header 'X-Missing' not found")
+		assert.EqualError(t, httptestclient.CaptureCookie("c", "missing")(resp, vars), "cookie 'missing' not found")
+		assert.Empty(t, vars)
+	})
+}
+
+func Test_scenario_steps_run_in_order_without_subtests(t *testing.T) {
+	var paths []string
+	target := httptestclient.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	ht := &hookedT{}
+	s := httptestclient.NewScenario(ht, target)
+	s.Step("first", func(c *httptestclient.Client) *httptestclient.Client {
+		return c.Get("/first")
+	})
+	s.Step("missing", func(c *httptestclient.Client) *httptestclient.Client {
+		return c.Get("/missing")
+	})
+	resp := s.Step("never", func(c *httptestclient.Client) *httptestclient.Client {
+		return c.Get("/never")
+	})
+	ht.finish()
+
+	assert.Equal(t, []string{"/first", "/missing"}, paths)
+	assert.Equal(t, []string{"expected success, got 404"}, ht.errors)
+	assert.Zero(t, resp.Status)
+	require.NotEmpty(t, ht.logs)
+	assert.Contains(t, ht.logs[0], "=== step 'first' ===")
+}
@@ -0,0 +1,113 @@
+package httptestclient
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"sync"
+)
+
+// Session owns the http.Client, cookie jar and redirect policy used to send requests to a Target.
+// Each Client creates its own Session on first use so that clients never share state, to share cookies
+// between clients create a Session and pass it to DoTarget or DoSimpleTarget, a Session is itself a Target
+type Session struct {
+	target  Target
+	client  *http.Client
+	openAPI *OpenAPI
+
+	mu        sync.Mutex
+	exchanges []*exchange
+	exportHAR bool
+}
+
+// NewSession for target, the targets http.Client is copied, and its transport cloned, so that the target
+// itself is never modified. Close the idle connections with Client().CloseIdleConnections() when done, the
+// session a Client creates for itself is closed when the test ends
+func NewSession(target Target) *Session {
+	base := target.Client()
+	jar := base.Jar
+	if jar == nil {
+		// cookiejar.New() NEVER returns an error
+		jar, _ = cookiejar.New(nil)
+	}
+	return &Session{
+		target: target,
+		client: &http.Client{
+			Transport:     cloneTransport(base.Transport),
+			CheckRedirect: base.CheckRedirect,
+			Jar:           jar,
+			Timeout:       base.Timeout,
+		},
+	}
+}
+
+// BaseURL of the target
+func (s *Session) BaseURL() string { return s.target.BaseURL() }
+
+// Client owned by the session
+func (s *Session) Client() *http.Client { return s.client }
+
+// Cookies the session would send to path, path is relative to the BaseURL
+func (s *Session) Cookies(path string) []*http.Cookie {
+	u, err := url.Parse(joinPath(s.BaseURL(), path))
+	if err != nil {
+		return nil
+	}
+	return s.client.Jar.Cookies(u)
+}
+
+// add an exchange made by any Client using the session, exchanges are only kept for ExportHAR
+func (s *Session) add(e *exchange) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.exportHAR {
+		s.exchanges = append(s.exchanges, e)
+	}
+}
+
+// exportingHAR true once ExportHAR has been called, the bodies of every exchange are then kept
+func (s *Session) exportingHAR() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.exportHAR
+}
+
+// sessionFor target, reusing the clients previous session when the target has not changed
+func (c *Client) sessionFor(target Target) *Session {
+	if s, ok := target.(*Session); ok {
+		return s
+	}
+	if c.session == nil || !sameTarget(c.session.target, target) {
+		if c.session != nil {
+			c.session.client.CloseIdleConnections()
+		}
+		c.session = NewSession(target)
+		// the transport is the clients own, its keep-alive connections would otherwise stay open
+		if h, ok := c.t.(testingHooks); ok {
+			h.Cleanup(c.session.client.CloseIdleConnections)
+		}
+	}
+	return c.session
+}
+
+// sameTarget true when both targets send to the same place, Server creates a new Target on every call
+func sameTarget(a, b Target) bool {
+	as, aok := a.(*serverTarget)
+	bs, bok := b.(*serverTarget)
+	if aok && bok {
+		return as.server == bs.server
+	}
+	return a == b
+}
+
+// cloneTransport so that connection state and TLS config are not shared with the source
+func cloneTransport(rt http.RoundTripper) http.RoundTripper {
+	switch tr := rt.(type) {
+	case nil:
+		return http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		return tr.Clone()
+	default:
+		return rt
+	}
+}
@@ -0,0 +1,123 @@
+package httptestclient_test
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NearlyUnique/httptestclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func cookieHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/set-cookie":
+			http.SetCookie(w, &http.Cookie{Name: "the_cookie", Value: r.URL.Query().Get("value")})
+		case "/send-cookie":
+			c, err := r.Cookie("the_cookie")
+			if err != nil {
+				_, _ = fmt.Fprint(w, "no-cookie")
+				return
+			}
+			_, _ = fmt.Fprint(w, c.Value)
+		case "/start":
+			http.Redirect(w, r, "/redirected", http.StatusSeeOther)
+		case "/redirected":
+			_, _ = fmt.Fprint(w, "done")
+		}
+	})
+}
+
+func Test_clients_are_isolated_from_each_other(t *testing.T) {
+	s := httptest.NewServer(cookieHandler())
+	defer s.Close()
+
+	t.Run("cookies do not leak between clients", func(t *testing.T) {
+		_ = httptestclient.New(t).Get("/set-cookie?value=first").DoSimple(s)
+
+		resp := httptestclient.New(t).Get("/send-cookie").DoSimple(s)
+
+		assert.Equal(t, "no-cookie", resp.Body)
+	})
+	t.Run("the server client is not modified", func(t *testing.T) {
+		_ = httptestclient.New(t).Get("/start").ExpectRedirectTo("/redirected").DoSimple(s)
+
+		assert.Nil(t, s.Client().Jar)
+		assert.Nil(t, s.Client().CheckRedirect)
+	})
+	t.Run("redirect expectations apply to each client", func(t *testing.T) {
+		for i := 0; i < 5; i++ {
+			t.Run(fmt.Sprintf("parallel client %d", i), func(t *testing.T) {
+				t.Parallel()
+				resp := httptestclient.New(t).
+					Get("/start").
+					ExpectRedirectTo("/redirected").
+					DoSimple(s)
+
+				assert.Equal(t, "done", resp.Body)
+				assert.Equal(t, "/redirected", resp.RedirectedVia)
+			})
+		}
+	})
+	t.Run("redirect expectations apply to each request from the same client", func(t *testing.T) {
+		client := httptestclient.New(t).Get("/start").ExpectRedirectTo("/redirected")
+
+		first := client.DoSimple(s)
+		second := client.DoSimple(s)
+
+		assert.Equal(t, "/redirected", first.RedirectedVia)
+		assert.Equal(t, "/redirected", second.RedirectedVia)
+	})
+}
+
+func Test_client_connections_are_closed_when_the_test_ends(t *testing.T) {
+	var mu sync.Mutex
+	open := map[net.Conn]bool{}
+	s := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	s.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		mu.Lock()
+		defer mu.Unlock()
+		if state == http.StateClosed || state == http.StateHijacked {
+			delete(open, conn)
+			return
+		}
+		open[conn] = true
+	}
+	s.Start()
+	defer s.Close()
+	openConnections := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(open)
+	}
+
+	t.Run("clients", func(t *testing.T) {
+		for i := 0; i < 50; i++ {
+			_ = httptestclient.New(t).Get("/").DoSimple(s)
+		}
+		assert.Equal(t, 50, openConnections())
+	})
+
+	assert.Eventually(t, func() bool { return openConnections() == 0 }, time.Second, 10*time.Millisecond)
+}
+
+func Test_a_session_shares_cookies_between_clients(t *testing.T) {
+	s := httptest.NewServer(cookieHandler())
+	defer s.Close()
+
+	session := httptestclient.NewSession(httptestclient.Server(s))
+
+	_ = httptestclient.New(t).Get("/set-cookie?value=shared").DoSimpleTarget(session)
+	resp := httptestclient.New(t).Get("/send-cookie").DoSimpleTarget(session)
+
+	assert.Equal(t, "shared", resp.Body)
+	cookies := session.Cookies("/")
+	if assert.Len(t, cookies, 1) {
+		assert.Equal(t, "the_cookie", cookies[0].Name)
+	}
+}
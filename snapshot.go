@@ -0,0 +1,204 @@
+package httptestclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// SnapshotDir where snapshots are stored, relative to the package under test
+var SnapshotDir = filepath.Join("testdata", "snapshots")
+
+// UpdateSnapshotsEnv when true, e.g. HTTPTESTCLIENT_UPDATE_SNAPSHOTS=1, snapshots are rewritten rather than compared
+const UpdateSnapshotsEnv = "HTTPTESTCLIENT_UPDATE_SNAPSHOTS"
+
+// redacted replaces volatile values in snapshots
+const redacted = "<redacted>"
+
+var (
+	rxUUID      = regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`)
+	rxTimestamp = regexp.MustCompile(`\b\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?\b`)
+)
+
+// SnapshotOption configures MatchSnapshot
+type SnapshotOption func(*snapshotConfig)
+
+type snapshotConfig struct {
+	headers       []string
+	redactHeaders []string
+	jsonPaths     []string
+	patterns      []redactPattern
+}
+
+type redactPattern struct {
+	rx          *regexp.Regexp
+	replacement string
+}
+
+// SnapshotHeaders to include in the snapshot, the default is Content-Type only
+func SnapshotHeaders(names ...string) SnapshotOption {
+	return func(c *snapshotConfig) {
+		c.headers = names
+	}
+}
+
+// RedactHeaders values are replaced with "<redacted>", Date is always redacted
+func RedactHeaders(names ...string) SnapshotOption {
+	return func(c *snapshotConfig) {
+		c.redactHeaders = append(c.redactHeaders, names...)
+	}
+}
+
+// RedactJSONPaths values are replaced with "<redacted>", see SimpleResponse.JSONPath for the syntax
+func RedactJSONPaths(paths ...string) SnapshotOption {
+	return func(c *snapshotConfig) {
+		c.jsonPaths = append(c.jsonPaths, paths...)
+	}
+}
+
+// RedactPattern matches in headers and body are replaced
+func RedactPattern(rx *regexp.Regexp, replacement string) SnapshotOption {
+	return func(c *snapshotConfig) {
+		c.patterns = append(c.patterns, redactPattern{rx: rx, replacement: replacement})
+	}
+}
+
+// RedactUUIDs replaces any UUID with "<uuid>"
+func RedactUUIDs() SnapshotOption {
+	return RedactPattern(rxUUID, "<uuid>")
+}
+
+// RedactTimestamps replaces RFC 3339 style timestamps with "<timestamp>"
+func RedactTimestamps() SnapshotOption {
+	return RedactPattern(rxTimestamp, "<timestamp>")
+}
+
+// MatchSnapshot compares the status, selected headers and normalized body with the snapshot stored as
+// SnapshotDir/name.snap, run the tests with HTTPTESTCLIENT_UPDATE_SNAPSHOTS=1 to create or rewrite the snapshots
+func (r SimpleResponse) MatchSnapshot(t TestingT, name string, opts ...SnapshotOption) SimpleResponse {
+	if h, ok := t.(testingHooks); ok {
+		h.Helper()
+	}
+	if r.t == nil {
+		return r
+	}
+	cfg := snapshotConfig{
+		headers:       []string{"Content-Type"},
+		redactHeaders: []string{"Date"},
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	actual, err := r.snapshot(cfg)
+	if err != nil {
+		t.Errorf("snapshot '%s' failed: %v", name, err)
+		return r
+	}
+
+	path := filepath.Join(SnapshotDir, filepath.FromSlash(name)+".snap")
+	if updateSnapshots() {
+		err = os.MkdirAll(filepath.Dir(path), 0o755)
+		if err == nil {
+			err = os.WriteFile(path, []byte(actual), 0o644)
+		}
+		if err != nil {
+			t.Errorf("snapshot '%s' could not be written: %v", path, err)
+		}
+		return r
+	}
+	expected, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("snapshot '%s' does not exist, run with HTTPTESTCLIENT_UPDATE_SNAPSHOTS=1 to create it", path)
+		return r
+	}
+	if err != nil {
+		t.Errorf("snapshot '%s' could not be read: %v", path, err)
+		return r
+	}
+	if string(expected) != actual {
+		diff, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        difflib.SplitLines(string(expected)),
+			B:        difflib.SplitLines(actual),
+			FromFile: path,
+			ToFile:   "actual",
+			Context:  3,
+		})
+		t.Errorf("snapshot '%s' does not match, run with HTTPTESTCLIENT_UPDATE_SNAPSHOTS=1 to accept\n%s", path, strings.TrimRight(diff, " \n"))
+	}
+	return r
+}
+
+// updateSnapshots from the environment, see UpdateSnapshotsEnv
+func updateSnapshots() bool {
+	update, _ := strconv.ParseBool(os.Getenv(UpdateSnapshotsEnv))
+	return update
+}
+
+// snapshot text of the response after redaction
+func (r SimpleResponse) snapshot(cfg snapshotConfig) (string, error) {
+	var sb strings.Builder
+	_, _ = fmt.Fprintf(&sb, "HTTP %d\n", r.Status)
+	for _, name := range cfg.headers {
+		for _, v := range r.Header.Values(name) {
+			for _, rh := range cfg.redactHeaders {
+				if strings.EqualFold(rh, name) {
+					v = redacted
+				}
+			}
+			_, _ = fmt.Fprintf(&sb, "%s: %s\n", http.CanonicalHeaderKey(name), v)
+		}
+	}
+	sb.WriteString("\n")
+
+	body, err := normalizeSnapshotBody(r.Body, cfg.jsonPaths)
+	if err != nil {
+		return "", err
+	}
+	sb.WriteString(body)
+
+	out := sb.String()
+	for _, p := range cfg.patterns {
+		out = p.rx.ReplaceAllString(out, p.replacement)
+	}
+	if !strings.HasSuffix(out, "\n") {
+		out += "\n"
+	}
+	return out, nil
+}
+
+// normalizeSnapshotBody json is indented with sorted keys, anything else is unchanged
+func normalizeSnapshotBody(body string, redactPaths []string) (string, error) {
+	// numbers are kept as written, a changed large id must change the snapshot
+	doc, err := decodeJSON([]byte(body), true)
+	if err != nil {
+		if len(redactPaths) > 0 {
+			return "", errors.New("json paths can only be redacted from a json body")
+		}
+		return body, nil
+	}
+	for _, p := range redactPaths {
+		jp, err := compileJSONPath(p)
+		if err != nil {
+			return "", fmt.Errorf("invalid json path '%s': %w", p, err)
+		}
+		doc = jp.replace(doc, redacted)
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
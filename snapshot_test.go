@@ -0,0 +1,124 @@
+package httptestclient
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/NearlyUnique/httptestclient/internal/self"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func useSnapshotDir(t *testing.T, update bool) string {
+	dir := t.TempDir()
+	previousDir := SnapshotDir
+	SnapshotDir = dir
+	t.Setenv(UpdateSnapshotsEnv, strconv.FormatBool(update))
+	t.Cleanup(func() {
+		SnapshotDir = previousDir
+	})
+	return dir
+}
+
+func snapshotResponse(body string) SimpleResponse {
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set("Date", "Mon, 02 Jan 2006 15:04:05 GMT")
+	header.Set("X-Request-Id", "9b2c7c4e-1f7d-4bb8-a9a1-1c7c2b1e0f3a")
+	return SimpleResponse{Header: header, Status: http.StatusOK, Body: body, t: self.NewFakeTester(func(string, ...interface{}) {})}
+}
+
+func Test_snapshots_are_written_when_updating(t *testing.T) {
+	dir := useSnapshotDir(t, true)
+
+	snapshotResponse(`{"id":"9b2c7c4e-1f7d-4bb8-a9a1-1c7c2b1e0f3a","b":2,"a":{"at":"2024-01-02T03:04:05Z"},"secret":"x"}`).
+		MatchSnapshot(t, "nested/name",
+			SnapshotHeaders("Content-Type", "Date", "X-Request-Id"),
+			RedactUUIDs(),
+			RedactTimestamps(),
+			RedactJSONPaths("$.secret"),
+		)
+
+	buf, err := os.ReadFile(filepath.Join(dir, "nested", "name.snap"))
+	require.NoError(t, err)
+	assert.Equal(t, `HTTP 200
+Content-Type: application/json
+Date: <redacted>
+X-Request-Id: <uuid>
+
+{
+  "a": {
+    "at": "<timestamp>"
+  },
+  "b": 2,
+  "id": "<uuid>",
+  "secret": "<redacted>"
+}
+`, string(buf))
+}
+
+func Test_snapshots_are_compared(t *testing.T) {
+	dir := useSnapshotDir(t, false)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "name.snap"), []byte("HTTP 200\nContent-Type: application/json\n\n{\n  \"a\": 1\n}\n"), 0o644))
+
+	t.Run("matching snapshot passes", func(t *testing.T) {
+		snapshotResponse(`{"a":1}`).MatchSnapshot(t, "name")
+	})
+	t.Run("a different response fails with a diff", func(t *testing.T) {
+		var errs []string
+		fake := self.NewFakeTester(func(format string, args ...interface{}) {
+			errs = append(errs, fmt.Sprintf(format, args...))
+		})
+
+		snapshotResponse(`{"a":2}`).MatchSnapshot(fake, "name")
+
+		path := filepath.Join(dir, "name.snap")
+		require.Len(t, errs, 1)
+		assert.Equal(t, fmt.Sprintf(`snapshot '%s' does not match, run with HTTPTESTCLIENT_UPDATE_SNAPSHOTS=1 to accept
+--- %s
++++ actual
+@@ -2,6 +2,6 @@
+ Content-Type: application/json
+ 
+ {
+-  "a": 1
++  "a": 2
+ }`, path, path), errs[0])
+	})
+	t.Run("a missing snapshot fails", func(t *testing.T) {
+		var errs []string
+		fake := self.NewFakeTester(func(format string, args ...interface{}) {
+			errs = append(errs, fmt.Sprintf(format, args...))
+		})
+
+		snapshotResponse(`{}`).MatchSnapshot(fake, "missing")
+
+		assert.Equal(t, []string{fmt.Sprintf("snapshot '%s' does not exist, run with HTTPTESTCLIENT_UPDATE_SNAPSHOTS=1 to create it",
+			filepath.Join(dir, "missing.snap"))}, errs)
+	})
+}
+
+func Test_snapshot_numbers_are_kept_as_written(t *testing.T) {
+	first, err := snapshotResponse(`{"id":9007199254740993,"price":1.50}`).snapshot(snapshotConfig{})
+	require.NoError(t, err)
+	second, err := snapshotResponse(`{"id":9007199254740992,"price":1.50}`).snapshot(snapshotConfig{})
+	require.NoError(t, err)
+
+	assert.Contains(t, first, `"id": 9007199254740993,`)
+	assert.Contains(t, first, `"price": 1.50`)
+	assert.NotEqual(t, first, second)
+}
+
+func Test_non_json_bodies_are_snapshot_unchanged(t *testing.T) {
+	r := snapshotResponse("plain text at 2024-01-02 03:04:05")
+
+	actual, err := r.snapshot(snapshotConfig{patterns: []redactPattern{{rx: regexp.MustCompile(`plain`), replacement: "simple"}}})
+
+	require.NoError(t, err)
+	assert.Equal(t, "HTTP 200\n\nsimple text at 2024-01-02 03:04:05\n", actual)
+}
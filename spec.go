@@ -0,0 +1,207 @@
+package httptestclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Spec a file of requests run in order as a Scenario, YAML or JSON
+//
+//	vars:
+//	  name: widget
+//	requests:
+//	  - name: create
+//	    method: POST
+//	    url: /items
+//	    headers:
+//	      X-Tenant: ${ENV:TENANT:-test}
+//	    body: {"name": "${name}"}
+//	    expect:
+//	      status: 201
+//	      json:
+//	        $.name: widget
+//	    capture:
+//	      id: $.id
+//	      etag: header:ETag
+//	      session: cookie:session
+//	  - name: read
+//	    url: /items/${id}
+type Spec struct {
+	Vars     map[string]any `yaml:"vars" json:"vars"`
+	Requests []SpecRequest  `yaml:"requests" json:"requests"`
+}
+
+// SpecRequest a request and its expectations, the method defaults to GET. A body that is not a string is sent as json.
+// Captures are a json path, "header:Name" or "cookie:name"
+type SpecRequest struct {
+	Name    string            `yaml:"name" json:"name"`
+	Method  string            `yaml:"method" json:"method"`
+	URL     string            `yaml:"url" json:"url"`
+	Headers map[string]string `yaml:"headers" json:"headers"`
+	Query   map[string]string `yaml:"query" json:"query"`
+	Body    any               `yaml:"body" json:"body"`
+	Expect  SpecExpect        `yaml:"expect" json:"expect"`
+	Capture map[string]string `yaml:"capture" json:"capture"`
+}
+
+// SpecExpect of the response, when status is not set any success status is expected
+type SpecExpect struct {
+	Status       int               `yaml:"status" json:"status"`
+	Headers      map[string]string `yaml:"headers" json:"headers"`
+	BodyContains string            `yaml:"body_contains" json:"body_contains"`
+	JSON         map[string]any    `yaml:"json" json:"json"`
+}
+
+// RunSpecs runs each file matching glob, e.g. "testdata/*.yaml", as a subtest against target
+// `t` use `*testing.T`
+func RunSpecs(t TestingT, target Target, glob string) {
+	if h, ok := t.(testingHooks); ok {
+		h.Helper()
+	}
+	files, err := filepath.Glob(glob)
+	if err != nil {
+		t.Errorf("invalid spec glob '%s': %v", glob, err)
+		t.FailNow()
+		return
+	}
+	if len(files) == 0 {
+		t.Errorf("no spec files match '%s'", glob)
+		t.FailNow()
+		return
+	}
+	for _, file := range files {
+		subtest(t, strings.TrimSuffix(filepath.Base(file), filepath.Ext(file)), func(t TestingT) {
+			spec, err := LoadSpec(file)
+			if err != nil {
+				t.Errorf("%v", err)
+				t.FailNow()
+				return
+			}
+			spec.Run(t, target)
+		})
+	}
+}
+
+// LoadSpec from a YAML or JSON file, unknown fields are an error
+func LoadSpec(file string) (*Spec, error) {
+	buf, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var spec Spec
+	// yaml is a superset of json
+	dec := yaml.NewDecoder(bytes.NewReader(buf))
+	dec.KnownFields(true)
+	if err = dec.Decode(&spec); err != nil {
+		return nil, fmt.Errorf("spec '%s': %w", file, err)
+	}
+	for i, r := range spec.Requests {
+		if r.URL == "" {
+			return nil, fmt.Errorf("spec '%s': request %d has no url", file, i+1)
+		}
+		for name, from := range r.Capture {
+			if _, err = specCapture(name, from); err != nil {
+				return nil, fmt.Errorf("spec '%s': request %d: %w", file, i+1, err)
+			}
+		}
+	}
+	return &spec, nil
+}
+
+// Run the requests in order as steps of a Scenario, the expectations are checked within each step
+func (s *Spec) Run(t TestingT, target Target) {
+	if h, ok := t.(testingHooks); ok {
+		h.Helper()
+	}
+	sc := NewScenario(t, target)
+	for k, v := range s.Vars {
+		sc.Var(k, v)
+	}
+	for i, r := range s.Requests {
+		name := r.Name
+		if name == "" {
+			name = fmt.Sprintf("%d %s %s", i+1, r.method(), r.URL)
+		}
+		captures := []Capture{r.Expect.check}
+		for _, k := range slices.Sorted(maps.Keys(r.Capture)) {
+			c, _ := specCapture(k, r.Capture[k])
+			captures = append(captures, c)
+		}
+		sc.Step(name, r.build, captures...)
+	}
+}
+
+// check the headers, body and json of the response, failures are reported to the test of the step
+func (e SpecExpect) check(r SimpleResponse, _ map[string]any) error {
+	for _, k := range slices.Sorted(maps.Keys(e.Headers)) {
+		r.ExpectHeader(k, e.Headers[k])
+	}
+	if e.BodyContains != "" {
+		r.ExpectBodyContains(e.BodyContains)
+	}
+	for _, path := range slices.Sorted(maps.Keys(e.JSON)) {
+		r.ExpectJSONPath(path, e.JSON[path])
+	}
+	return nil
+}
+
+func (r SpecRequest) method() string {
+	if r.Method == "" {
+		return "GET"
+	}
+	return strings.ToUpper(r.Method)
+}
+
+// build the request on c, the status is checked by the Client
+func (r SpecRequest) build(c *Client) *Client {
+	c.Method(r.method()).URL(r.URL)
+	for k, v := range r.Headers {
+		c.Header(k, v)
+	}
+	for _, k := range slices.Sorted(maps.Keys(r.Query)) {
+		c.Query(k, r.Query[k])
+	}
+	switch body := r.Body.(type) {
+	case nil:
+	case string:
+		c.BodyString(body)
+	default:
+		// marshalled then sent as a string so that variables are expanded
+		buf, err := json.Marshal(body)
+		if c.hasError(err) {
+			return c
+		}
+		c.BodyString(string(buf))
+	}
+	if r.Expect.Status != 0 {
+		if r.Expect.Status >= 300 && r.Expect.Status < 400 {
+			c.NoFollowRedirects()
+		}
+		c.ExpectedStatusCode(r.Expect.Status)
+	}
+	return c
+}
+
+// specCapture from "$.path", "header:Name" or "cookie:name"
+func specCapture(name, from string) (Capture, error) {
+	switch {
+	case strings.HasPrefix(from, "$"):
+		if _, err := compileJSONPath(from); err != nil {
+			return nil, fmt.Errorf("capture '%s': invalid json path '%s': %w", name, from, err)
+		}
+		return CaptureJSONPath(name, from), nil
+	case strings.HasPrefix(from, "header:"):
+		return CaptureHeader(name, strings.TrimPrefix(from, "header:")), nil
+	case strings.HasPrefix(from, "cookie:"):
+		return CaptureCookie(name, strings.TrimPrefix(from, "cookie:")), nil
+	}
+	return nil, fmt.Errorf("capture '%s': '%s' must be a json path, header:Name or cookie:name", name, from)
+}
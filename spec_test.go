@@ -0,0 +1,63 @@
+package httptestclient_test
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/NearlyUnique/httptestclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_run_specs(t *testing.T) {
+	httptestclient.RunSpecs(t, httptestclient.Handler(itemServer()), "testdata/specs/*")
+}
+
+// failingSpecEnv set when Test_a_failing_spec_process is run by Test_a_failing_spec_fails_its_step
+const failingSpecEnv = "HTTPTESTCLIENT_FAILING_SPEC"
+
+func Test_a_failing_spec_process(t *testing.T) {
+	if os.Getenv(failingSpecEnv) == "" {
+		t.Skip("run by Test_a_failing_spec_fails_its_step")
+	}
+	httptestclient.RunSpecs(t, httptestclient.Handler(itemServer()), "testdata/failingspecs/*")
+}
+
+func Test_a_failing_spec_fails_its_step(t *testing.T) {
+	cmd := exec.Command(os.Args[0], "-test.run=^Test_a_failing_spec_process$", "-test.v")
+	cmd.Env = append(os.Environ(), failingSpecEnv+"=1")
+	out, err := cmd.CombinedOutput()
+	output := string(out)
+
+	require.Error(t, err, output)
+	assert.Contains(t, output, "--- PASS: Test_a_failing_spec_process/wrong_name/create")
+	assert.Contains(t, output, "--- FAIL: Test_a_failing_spec_process/wrong_name/read")
+	assert.Contains(t, output, `expected json path '$.name' to be "gadget", got "widget"`)
+	assert.Contains(t, output, "=== step 'create' ===")
+	assert.Contains(t, output, "--- SKIP: Test_a_failing_spec_process/wrong_name/delete")
+	assert.Contains(t, output, "skipped, step 'read' failed")
+}
+
+func Test_load_spec(t *testing.T) {
+	t.Run("json and yaml", func(t *testing.T) {
+		spec, err := httptestclient.LoadSpec("testdata/specs/no_session.json")
+
+		require.NoError(t, err)
+		assert.Equal(t, &httptestclient.Spec{Requests: []httptestclient.SpecRequest{{
+			Name:   "no session",
+			URL:    "/items/${ENV:MISSING_ITEM_ID:-999}",
+			Expect: httptestclient.SpecExpect{Status: 401},
+		}}}, spec)
+	})
+	t.Run("unknown fields are errors", func(t *testing.T) {
+		_, err := httptestclient.LoadSpec("testdata/badspecs/unknown_field.yaml")
+
+		assert.ErrorContains(t, err, "field expected not found")
+	})
+	t.Run("captures are validated", func(t *testing.T) {
+		_, err := httptestclient.LoadSpec("testdata/badspecs/bad_capture.yaml")
+
+		assert.EqualError(t, err, "spec 'testdata/badspecs/bad_capture.yaml': request 1: capture 'id': 'body.id' must be a json path, header:Name or cookie:name")
+	})
+}
@@ -2,42 +2,247 @@ package httptestclient
 
 import (
 	"fmt"
+	"net/url"
+	"os"
+	"reflect"
 	"regexp"
 	"strconv"
+	"strings"
 )
 
-var rxDollarEnv = regexp.MustCompile(`\$(?P<Key>[a-zA-Z0-9_]+)`)
+// rxVar ${NAME}, ${NAME:-default}, ${NAME-default}, ${ENV:NAME} and ${ENV:NAME:-default}
+const rxVar = `\$\{(?P<Env>ENV:)?(?P<Var>[a-zA-Z_][a-zA-Z0-9_.]*)(?::?-(?P<Default>[^}]*))?\}`
 
-func expandStr(msg string, args ...any) string {
-	return replaceAllStringSubMatchFunc(rxDollarEnv, msg, func(values []string) string {
-		if i, err := strconv.Atoi(values[1]); err == nil {
-			if i < 0 || i >= len(args) {
-				return fmt.Sprintf("[bad_index:$%d]", i)
-			}
-			return fmt.Sprintf("%v", args[i])
+// rxVarEscape $${ is sent as a literal ${
+const rxVarEscape = `\$\$\{|`
+
+var rxVariable = regexp.MustCompile(rxVarEscape + rxVar)
+
+// rxURLParam variables, $key, {key}, {key...} and {$} as used by http.ServeMux patterns, keys may be nested, $Key.Field
+var rxURLParam = regexp.MustCompile(rxVarEscape + rxVar + `|\$(?P<Key>[a-zA-Z0-9_]+(?:\.[a-zA-Z_][a-zA-Z0-9_]*)*)|\{(?P<Brace>[a-zA-Z0-9_]+(?:\.[a-zA-Z_][a-zA-Z0-9_]*)*)(?P<Rest>\.\.\.)?\}|\{\$\}`)
+
+// Raw values are inserted into a URL pattern without escaping, e.g. URL("/files/$0", Raw("a/b.txt"))
+type Raw string
+
+// expandVars replaces ${NAME} from vars and ${ENV:NAME} from the environment, either may have a default,
+// ${NAME:-default}, $${NAME} is a literal ${NAME}. Any variables without a value or default are returned as missing
+func expandVars(msg string, vars map[string]any) (string, []string) {
+	var missing []string
+	result := replaceAllStringSubMatchFunc(rxVariable, msg, func(values []string) string {
+		if values[0] == "$${" {
+			return "${"
+		}
+		v, ok := lookupVar(values, vars)
+		if !ok {
+			missing = append(missing, values[0])
+			return missingMarker(values[2], values[0])
+		}
+		return fmt.Sprintf("%v", v)
+	})
+	return result, missing
+}
+
+// escapeVars so that expandVars leaves any ${NAME} in s as it is
+func escapeVars(s string) string {
+	return strings.ReplaceAll(s, "${", "$${")
+}
+
+// hasVariable true if any placeholder is a ${NAME} variable
+func hasVariable(placeholders []string) bool {
+	for _, p := range placeholders {
+		if strings.HasPrefix(p, "${") {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupVar using the groups of rxVar
+func lookupVar(values []string, vars map[string]any) (any, bool) {
+	env, name, def := values[1], values[2], values[3]
+	var (
+		v  any
+		ok bool
+	)
+	if env != "" {
+		v, ok = os.LookupEnv(name)
+	} else if arg, rest, found := lookupArg(name, []any{vars}); found && rest == "" {
+		v, ok = arg, true
+	}
+	// as in a shell, ":-" uses the default when unset or empty and "-" only when unset
+	switch op := values[0][len(env)+len(name)+2:]; {
+	case strings.HasPrefix(op, ":-") && (!ok || fmt.Sprint(v) == ""):
+		return def, true
+	case strings.HasPrefix(op, "-") && !ok:
+		return def, true
+	}
+	return v, ok
+}
+
+// expandURL replaces $index, $key, {key} and {key...} from args, a single map or struct argument may be used for keys.
+// Each value is escaped for its position, path or query, unless it is Raw.
+// {key...} may contain '/', each segment is escaped. Variables are expanded as per expandVars.
+// Any placeholders without a value are returned as missing
+func expandURL(pattern string, vars map[string]any, args ...any) (string, []string) {
+	query := strings.IndexByte(pattern, '?')
+	var missing []string
+	result := replaceAllStringSubMatchIndexFunc(rxURLParam, pattern, func(start int, values []string) string {
+		switch values[0] {
+		case "{$}":
+			return ""
+		case "$${":
+			return "${"
+		}
+		var (
+			v    any
+			rest string
+			ok   bool
+		)
+		key := values[4] + values[5]
+		if values[2] != "" {
+			key = values[2]
+			v, ok = lookupVar(values[:4], vars)
+		} else {
+			v, rest, ok = lookupArg(key, args)
 		}
-		if len(args) == 1 {
-			if m, ok := args[0].(map[string]any); ok {
-				if v, ok := m[values[1]]; ok {
-					return fmt.Sprintf("%v", v)
-				}
+		if !ok {
+			missing = append(missing, values[0])
+			return missingMarker(key, values[0])
+		}
+		if r, ok := v.(Raw); ok {
+			return string(r) + rest
+		}
+		s := fmt.Sprintf("%v", v)
+		switch {
+		case query >= 0 && start > query:
+			return url.QueryEscape(s) + rest
+		case values[6] != "":
+			segments := strings.Split(s, "/")
+			for i := range segments {
+				segments[i] = url.PathEscape(segments[i])
 			}
+			return strings.Join(segments, "/") + rest
+		default:
+			return url.PathEscape(s) + rest
 		}
-		return fmt.Sprintf("[no_key:$%s]", values[1])
 	})
+	return result, missing
+}
+
+// lookupArg by index or, when there is a single map or struct argument, by key or field name.
+// Keys may be nested, "0.Customer.ID" or "Customer.ID", rest is the part of key that could not be
+// resolved, e.g. ".json" in "$name.json", and should be treated as literal text
+func lookupArg(key string, args []any) (value any, rest string, ok bool) {
+	parts := strings.Split(key, ".")
+	if i, err := strconv.Atoi(parts[0]); err == nil {
+		if i < 0 || i >= len(args) {
+			return nil, "", false
+		}
+		value = args[i]
+	} else {
+		if len(args) != 1 {
+			return nil, "", false
+		}
+		if value, ok = member(args[0], parts[0]); !ok {
+			return nil, "", false
+		}
+	}
+	for i, p := range parts[1:] {
+		next, ok := member(value, p)
+		if !ok {
+			return deref(value), "." + strings.Join(parts[i+1:], "."), true
+		}
+		value = next
+	}
+	return deref(value), "", true
+}
+
+// deref pointers so the value is formatted rather than the address, unless the pointer is a fmt.Stringer
+func deref(v any) any {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		if _, ok := rv.Interface().(fmt.Stringer); ok {
+			break
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return v
+	}
+	return rv.Interface()
+}
+
+// member of a map with string keys or a struct, struct fields match a `path:"name"` tag or the exported field name
+func member(v any, name string) (any, bool) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, false
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, false
+		}
+		mv := rv.MapIndex(reflect.ValueOf(name).Convert(rv.Type().Key()))
+		if !mv.IsValid() {
+			return nil, false
+		}
+		return mv.Interface(), true
+	case reflect.Struct:
+		for _, f := range reflect.VisibleFields(rv.Type()) {
+			if f.IsExported() && f.Tag.Get("path") == name {
+				return fieldValue(rv, f.Index)
+			}
+		}
+		if f, ok := rv.Type().FieldByName(name); ok && f.IsExported() && f.Tag.Get("path") == "" {
+			return fieldValue(rv, f.Index)
+		}
+	}
+	return nil, false
+}
+
+// fieldValue false when reached through a nil embedded pointer
+func fieldValue(rv reflect.Value, index []int) (any, bool) {
+	fv, err := rv.FieldByIndexErr(index)
+	if err != nil {
+		return nil, false
+	}
+	return fv.Interface(), true
+}
+
+func missingMarker(key, placeholder string) string {
+	if _, err := strconv.Atoi(key); err == nil {
+		return fmt.Sprintf("[bad_index:%s]", placeholder)
+	}
+	return fmt.Sprintf("[no_key:%s]", placeholder)
 }
 
 func replaceAllStringSubMatchFunc(re *regexp.Regexp, str string, repl func(args []string) string) string {
+	return replaceAllStringSubMatchIndexFunc(re, str, func(_ int, args []string) string {
+		return repl(args)
+	})
+}
+
+// replaceAllStringSubMatchIndexFunc as replaceAllStringSubMatchFunc, start is the index of the match in str.
+// Groups that did not participate in the match are empty
+func replaceAllStringSubMatchIndexFunc(re *regexp.Regexp, str string, repl func(start int, args []string) string) string {
 	result := ""
 	lastIndex := 0
 
-	for _, v := range re.FindAllSubmatchIndex([]byte(str), -1) {
+	for _, v := range re.FindAllStringSubmatchIndex(str, -1) {
 		var groups []string
 		for i := 0; i < len(v); i += 2 {
+			if v[i] < 0 {
+				groups = append(groups, "")
+				continue
+			}
 			groups = append(groups, str[v[i]:v[i+1]])
 		}
 
-		result += str[lastIndex:v[0]] + repl(groups)
+		result += str[lastIndex:v[0]] + repl(v[0], groups)
 		lastIndex = v[1]
 	}
 
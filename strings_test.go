@@ -13,19 +13,23 @@ func Test_basic_map_can_be_expanded(t *testing.T) {
 		"Mixed": true,
 		"aZ_09": -1,
 	}
-	actual := expandStr("before $lower$UPPER ($Mixed) $aZ_09 after $UNKNOWN", keys)
+	actual, missing := expandURL("before $lower$UPPER ($Mixed) $aZ_09 after $UNKNOWN", nil, keys)
 
 	assert.Equal(t, "before 1two (true) -1 after [no_key:$UNKNOWN]", actual)
+	assert.Equal(t, []string{"$UNKNOWN"}, missing)
 }
 
-func Test_when_no_expansions_are_supplied_expandStr_is_identity_function(t *testing.T) {
-	assert.Equal(t, "no change", expandStr("no change"))
-	assert.Equal(t, "", expandStr(""))
+func Test_when_no_expansions_are_supplied_expandURL_is_identity_function(t *testing.T) {
+	actual, missing := expandURL("no change", nil)
+	assert.Equal(t, "no change", actual)
+	assert.Empty(t, missing)
+	actual, _ = expandURL("", nil)
+	assert.Equal(t, "", actual)
 }
 
 func Test_expansion_can_use_indexed_argument_values(t *testing.T) {
 	a := someType{}
-	actual := expandStr("$0, $1$2 @@$3@@ $99", 10, "any", true, a)
+	actual, _ := expandURL("$0, $1$2 @@$3@@ $99", nil, 10, "any", true, a)
 
 	assert.Equal(t, "10, anytrue @@has-stringer@@ [bad_index:$99]", actual)
 }
@@ -33,3 +37,135 @@ func Test_expansion_can_use_indexed_argument_values(t *testing.T) {
 type someType struct{}
 
 func (someType) String() string { return "has-stringer" }
+
+func Test_url_expansion_escapes_values(t *testing.T) {
+	testData := []struct {
+		pattern  string
+		args     []any
+		expected string
+	}{
+		{"/a/$0/b", []any{"x/y?z w"}, "/a/x%2Fy%3Fz%20w/b"},
+		{"/a/$0", []any{"café"}, "/a/caf%C3%A9"},
+		{"/a/$0", []any{Raw("x/y")}, "/a/x/y"},
+		{"/a/$0?q=$1&n=$2", []any{"p q", "a&b=c d", 5}, "/a/p%20q?q=a%26b%3Dc+d&n=5"},
+		{"/users/{id}/orders/{order}", []any{map[string]any{"id": 7, "order": "a b"}}, "/users/7/orders/a%20b"},
+		{"/users/{0}/{1}", []any{"x", "y"}, "/users/x/y"},
+		{"/files/{path...}", []any{map[string]any{"path": "dir one/file?.txt"}}, "/files/dir%20one/file%3F.txt"},
+		{"/exact/{$}", nil, "/exact/"},
+		{"/$0/{missing}/$key", []any{"x"}, "/x/[no_key:{missing}]/[no_key:$key]"},
+		{"/$3", []any{"x"}, "/[bad_index:$3]"},
+	}
+	for _, td := range testData {
+		t.Run(td.pattern, func(t *testing.T) {
+			actual, _ := expandURL(td.pattern, nil, td.args...)
+
+			assert.Equal(t, td.expected, actual)
+		})
+	}
+}
+
+func Test_url_expansion_reports_missing_values(t *testing.T) {
+	_, missing := expandURL("/$0/{name}/$1/{$}", nil, "x")
+
+	assert.Equal(t, []string{"{name}", "$1"}, missing)
+}
+
+type Customer struct {
+	ID   int
+	Name string `path:"customer_name"`
+}
+
+type Audit struct {
+	By string
+}
+
+type Order struct {
+	*Audit
+	Customer Customer
+	OrderID  string
+	Ref      *string `path:"ref"`
+	Lines    map[string]int
+	internal string
+}
+
+func Test_url_expansion_from_structs(t *testing.T) {
+	ref := "r/1"
+	order := Order{
+		Audit:    &Audit{By: "bob"},
+		Customer: Customer{ID: 7, Name: "a b"},
+		OrderID:  "o-1",
+		Ref:      &ref,
+		Lines:    map[string]int{"apples": 3},
+		internal: "never",
+	}
+	testData := []struct {
+		pattern  string
+		arg      any
+		expected string
+	}{
+		{"/customers/$Customer.ID/orders/$OrderID", order, "/customers/7/orders/o-1"},
+		{"/customers/{Customer.customer_name}", &order, "/customers/a%20b"},
+		{"/refs/$ref/by/$By", order, "/refs/r%2F1/by/bob"},
+		{"/lines/$Lines.apples", order, "/lines/3"},
+		{"/orders/$OrderID.json", order, "/orders/o-1.json"},
+		{"/orders/$0.OrderID/$1", []any{order, "x"}, "/orders/o-1/x"},
+		{"/$internal/$Name/$By", Order{}, "/[no_key:$internal]/[no_key:$Name]/[no_key:$By]"},
+		{"/typed/$name", map[string]string{"name": "v"}, "/typed/v"},
+	}
+	for _, td := range testData {
+		t.Run(td.pattern, func(t *testing.T) {
+			args := []any{td.arg}
+			if a, ok := td.arg.([]any); ok {
+				args = a
+			}
+			actual, _ := expandURL(td.pattern, nil, args...)
+
+			assert.Equal(t, td.expected, actual)
+		})
+	}
+}
+
+func Test_variable_expansion(t *testing.T) {
+	t.Setenv("HTC_TEST_HOST", "api.example")
+	t.Setenv("HTC_TEST_EMPTY", "")
+	vars := map[string]any{
+		"name":     "a b",
+		"customer": Customer{ID: 7},
+		"empty":    "",
+	}
+	testData := []struct {
+		template string
+		expected string
+		missing  []string
+	}{
+		{"${name}", "a b", nil},
+		{"${customer.ID}", "7", nil},
+		{"${empty:-fallback}", "fallback", nil},
+		{"${unset:-fallback}", "fallback", nil},
+		{"${empty-fallback}", "", nil},
+		{"${unset-fallback}", "fallback", nil},
+		{"${unset:-}", "", nil},
+		{"https://${ENV:HTC_TEST_HOST}/", "https://api.example/", nil},
+		{"${ENV:HTC_TEST_UNSET:-local}", "local", nil},
+		{"${ENV:HTC_TEST_EMPTY:-local}", "local", nil},
+		{"${ENV:HTC_TEST_EMPTY-local}", "", nil},
+		{"${ENV:name}", "[no_key:${ENV:name}]", []string{"${ENV:name}"}},
+		{"${unset} and ${customer.Missing}", "[no_key:${unset}] and [no_key:${customer.Missing}]", []string{"${unset}", "${customer.Missing}"}},
+		{"$name {name} $$", "$name {name} $$", nil},
+	}
+	for _, td := range testData {
+		t.Run(td.template, func(t *testing.T) {
+			actual, missing := expandVars(td.template, vars)
+
+			assert.Equal(t, td.expected, actual)
+			assert.Equal(t, td.missing, missing)
+		})
+	}
+}
+
+func Test_url_expansion_with_variables(t *testing.T) {
+	actual, missing := expandURL("/${name}/$0?q=${name}&x=${unset}", map[string]any{"name": "a b"}, "c")
+
+	assert.Equal(t, "/a%20b/c?q=a+b&x=[no_key:${unset}]", actual)
+	assert.Equal(t, []string{"${unset}"}, missing)
+}
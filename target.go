@@ -0,0 +1,85 @@
+package httptestclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+)
+
+// handlerBaseURL used for requests executed in-process against a http.Handler, matches httptest.NewRequest
+const handlerBaseURL = "http://example.com"
+
+// Target where requests are sent, see Server, Handler and BaseURL
+type Target interface {
+	// BaseURL all request paths are relative to
+	BaseURL() string
+	// Client used to send the request
+	Client() *http.Client
+}
+
+type serverTarget struct {
+	server *httptest.Server
+}
+
+// Server targets a running httptest.Server, this is what Client.Do uses
+func Server(s *httptest.Server) Target {
+	return &serverTarget{server: s}
+}
+
+func (s *serverTarget) BaseURL() string      { return s.server.URL }
+func (s *serverTarget) Client() *http.Client { return s.server.Client() }
+
+type handlerTarget struct {
+	client *http.Client
+}
+
+// Handler targets a http.Handler directly, no listener is started, each request is served in-process
+// using a httptest.ResponseRecorder
+func Handler(h http.Handler) Target {
+	return &handlerTarget{
+		client: &http.Client{Transport: handlerTransport{handler: h}},
+	}
+}
+
+func (h *handlerTarget) BaseURL() string      { return handlerBaseURL }
+func (h *handlerTarget) Client() *http.Client { return h.client }
+
+type urlTarget struct {
+	baseURL string
+	client  *http.Client
+}
+
+// BaseURL targets any server reachable at baseURL, e.g. "http://localhost:8080"
+// if client is nil a new http.Client is used
+func BaseURL(baseURL string, client *http.Client) Target {
+	if client == nil {
+		client = &http.Client{}
+	}
+	return &urlTarget{baseURL: strings.TrimSuffix(baseURL, "/"), client: client}
+}
+
+func (u *urlTarget) BaseURL() string      { return u.baseURL }
+func (u *urlTarget) Client() *http.Client { return u.client }
+
+// handlerTransport serves each request in-process
+type handlerTransport struct {
+	handler http.Handler
+}
+
+// RoundTrip as per http.RoundTripper, the request is made to look like a server side request
+func (h handlerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := req.Clone(req.Context())
+	r.RequestURI = req.URL.RequestURI()
+	r.RemoteAddr = "192.0.2.1:1234"
+	if r.Body == nil {
+		r.Body = http.NoBody
+	}
+	defer func() { _ = r.Body.Close() }()
+
+	rec := httptest.NewRecorder()
+	h.handler.ServeHTTP(rec, r)
+
+	resp := rec.Result()
+	resp.Request = req
+	return resp, nil
+}
@@ -0,0 +1,113 @@
+package httptestclient_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/NearlyUnique/httptestclient"
+	"github.com/NearlyUnique/httptestclient/internal/self"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_a_handler_can_be_targeted_without_a_server(t *testing.T) {
+	var actual struct {
+		url        string
+		requestURI string
+		payload    string
+	}
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actual.url = r.URL.Path
+		actual.requestURI = r.RequestURI
+		buf, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		actual.payload = string(buf)
+		w.Header().Set("a-header", "a-value")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`any content`))
+	})
+
+	resp := httptestclient.New(t).
+		Post("/some/$0", "path").
+		BodyString("any payload").
+		DoSimpleTarget(httptestclient.Handler(h))
+
+	assert.Equal(t, "/some/path", actual.url)
+	assert.Equal(t, "/some/path", actual.requestURI)
+	assert.Equal(t, "any payload", actual.payload)
+	assert.Equal(t, http.StatusCreated, resp.Status)
+	assert.Equal(t, "a-value", resp.Header.Get("a-header"))
+	assert.Equal(t, "any content", resp.Body)
+}
+
+func Test_all_targets_behave_the_same(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/set-cookie":
+			http.SetCookie(w, &http.Cookie{Name: "the_cookie", Value: "the_value"})
+		case "/send-cookie":
+			c, err := r.Cookie("the_cookie")
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			_, _ = fmt.Fprint(w, c.Value)
+		case "/start":
+			http.Redirect(w, r, "/redirected", http.StatusSeeOther)
+		case "/redirected":
+			_, _ = fmt.Fprint(w, "done")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	newServer := func(t *testing.T) *httptest.Server {
+		s := httptest.NewServer(handler)
+		t.Cleanup(s.Close)
+		return s
+	}
+
+	targets := map[string]func(t *testing.T) httptestclient.Target{
+		"server": func(t *testing.T) httptestclient.Target {
+			return httptestclient.Server(newServer(t))
+		},
+		"handler": func(t *testing.T) httptestclient.Target {
+			return httptestclient.Handler(handler)
+		},
+		"base url": func(t *testing.T) httptestclient.Target {
+			return httptestclient.BaseURL(newServer(t).URL+"/", nil)
+		},
+	}
+	for name, newTarget := range targets {
+		t.Run(name, func(t *testing.T) {
+			t.Run("cookies are sent on subsequent requests", func(t *testing.T) {
+				target := newTarget(t)
+				client := httptestclient.New(t)
+				_ = client.Get("/set-cookie").DoSimpleTarget(target)
+				resp := client.Get("/send-cookie").DoSimpleTarget(target)
+
+				assert.Equal(t, "the_value", resp.Body)
+			})
+			t.Run("redirects are followed", func(t *testing.T) {
+				resp := httptestclient.New(t).
+					Get("/start").
+					ExpectRedirectTo("/redirected").
+					DoSimpleTarget(newTarget(t))
+
+				assert.Equal(t, "done", resp.Body)
+				assert.Equal(t, "/redirected", resp.RedirectedVia)
+			})
+			t.Run("non 2xx fails", func(t *testing.T) {
+				_ = httptestclient.New(self.NewFakeTester(func(format string, args ...interface{}) {
+					assert.Equal(t, "expected success, got %d", format)
+					require.Equal(t, 1, len(args))
+					assert.Equal(t, http.StatusNotFound, args[0].(int))
+				})).
+					Get("/unknown").
+					DoSimpleTarget(newTarget(t))
+			})
+		})
+	}
+}